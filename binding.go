@@ -0,0 +1,234 @@
+package slim
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Content types of the built-in bindings.
+const (
+	MIMEJSON              = "application/json"
+	MIMEXML               = "application/xml"
+	MIMEXML2              = "text/xml"
+	MIMEPOSTForm          = "application/x-www-form-urlencoded"
+	MIMEMultipartPOSTForm = "multipart/form-data"
+)
+
+// Binding decodes the data of a request into a struct.
+// Custom formats are added with RegisterBinding.
+type Binding interface {
+	Name() string
+	Bind(req *http.Request, obj interface{}) error
+}
+
+// The built-in bindings, the struct fields are matched with the tag given in parenthesis.
+var (
+	BindingJSON      Binding = jsonBinding{}      // request body as JSON (json)
+	BindingXML       Binding = xmlBinding{}       // request body as XML (xml)
+	BindingForm      Binding = formBinding{}      // url query and url-encoded or multipart body (form)
+	BindingMultipart Binding = multipartBinding{} // multipart body including the uploaded files (form)
+	BindingQuery     Binding = queryBinding{}     // url query only (form)
+	BindingHeader    Binding = headerBinding{}    // request headers (header)
+)
+
+// bindings maps a content type to its binding, see RegisterBinding.
+var bindings = map[string]Binding{
+	MIMEJSON:              BindingJSON,
+	MIMEXML:               BindingXML,
+	MIMEXML2:              BindingXML,
+	MIMEPOSTForm:          BindingForm,
+	MIMEMultipartPOSTForm: BindingMultipart,
+}
+
+// RegisterBinding makes Context.Bind and Context.ShouldBind use b for the requests
+// whose Content-Type is contentType, replacing any previous binding, for example:
+//
+//	slim.RegisterBinding("application/x-yaml", yamlBinding{})
+//
+// It must be called before serving requests, typically from an init function.
+func RegisterBinding(contentType string, b Binding) {
+	if contentType == "" || b == nil {
+		panic("slim: RegisterBinding needs a content type and a binding")
+	}
+
+	bindings[strings.ToLower(contentType)] = b
+}
+
+// defaultBinding returns the binding for method and contentType,
+// the form binding is used for GET requests and unknown content types.
+func defaultBinding(method, contentType string) Binding {
+	if method == http.MethodGet {
+		return BindingForm
+	}
+
+	if b, ok := bindings[strings.ToLower(contentType)]; ok {
+		return b
+	}
+
+	return BindingForm
+}
+
+type jsonBinding struct{}
+
+func (jsonBinding) Name() string {
+	return "json"
+}
+
+func (jsonBinding) Bind(req *http.Request, obj interface{}) error {
+	if req == nil || req.Body == nil {
+		return errors.New("slim: invalid request, no body to bind")
+	}
+
+	return json.NewDecoder(req.Body).Decode(obj)
+}
+
+type xmlBinding struct{}
+
+func (xmlBinding) Name() string {
+	return "xml"
+}
+
+func (xmlBinding) Bind(req *http.Request, obj interface{}) error {
+	if req == nil || req.Body == nil {
+		return errors.New("slim: invalid request, no body to bind")
+	}
+
+	return xml.NewDecoder(req.Body).Decode(obj)
+}
+
+type formBinding struct{}
+
+func (formBinding) Name() string {
+	return "form"
+}
+
+func (formBinding) Bind(req *http.Request, obj interface{}) error {
+	if err := req.ParseMultipartForm(defaultMultipartMemory); err != nil && err != http.ErrNotMultipart {
+		return err
+	}
+
+	return mapValuesByTag(obj, req.Form, "form")
+}
+
+type multipartBinding struct{}
+
+func (multipartBinding) Name() string {
+	return "multipart/form-data"
+}
+
+func (multipartBinding) Bind(req *http.Request, obj interface{}) error {
+	if err := req.ParseMultipartForm(defaultMultipartMemory); err != nil {
+		return err
+	}
+
+	return mapMultipart(obj, req.MultipartForm)
+}
+
+type queryBinding struct{}
+
+func (queryBinding) Name() string {
+	return "query"
+}
+
+func (queryBinding) Bind(req *http.Request, obj interface{}) error {
+	return mapValuesByTag(obj, req.URL.Query(), "form")
+}
+
+type headerBinding struct{}
+
+func (headerBinding) Name() string {
+	return "header"
+}
+
+func (headerBinding) Bind(req *http.Request, obj interface{}) error {
+	return mapHeader(obj, req.Header)
+}
+
+// Bind decodes the request into obj with the binding chosen from the method and
+// Content-Type of the request, see ShouldBind.
+// On error the request is aborted with status 400 and the error is recorded
+// in Context.Errors as an ErrorTypeBind error, the Meta of ValidationErrors
+// gives the failing rule of each field.
+func (c *Context) Bind(obj interface{}) error {
+	return c.BindWith(obj, defaultBinding(c.Method, c.ContentType()))
+}
+
+// BindWith decodes the request into obj with b, like Bind.
+func (c *Context) BindWith(obj interface{}, b Binding) error {
+	if err := c.ShouldBindWith(obj, b); err != nil {
+		e := c.AbortWithError(http.StatusBadRequest, err).SetType(ErrorTypeBind)
+		if errs, ok := err.(ValidationErrors); ok {
+			e.SetMeta(errs.meta())
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// ShouldBind decodes the request into obj with the binding chosen from the method and
+// Content-Type of the request: the form binding for GET requests, then JSON, XML,
+// url-encoded form, multipart form or a binding added with RegisterBinding.
+// The decoded struct is then checked with Validate.
+// Unlike Bind, it leaves the error handling to the caller.
+func (c *Context) ShouldBind(obj interface{}) error {
+	return c.ShouldBindWith(obj, defaultBinding(c.Method, c.ContentType()))
+}
+
+// ShouldBindWith decodes the request into obj with b.
+func (c *Context) ShouldBindWith(obj interface{}, b Binding) error {
+	if b == BindingForm || b == BindingMultipart {
+		// keep the memory limit of the engine
+		if err := c.parseMultipartForm(); err != nil && err != http.ErrNotMultipart {
+			return err
+		}
+	}
+
+	if err := b.Bind(c.Request, obj); err != nil {
+		return err
+	}
+
+	return Validate(obj)
+}
+
+// ShouldBindJSON decodes the JSON request body into obj.
+func (c *Context) ShouldBindJSON(obj interface{}) error {
+	return c.ShouldBindWith(obj, BindingJSON)
+}
+
+// ShouldBindXML decodes the XML request body into obj.
+func (c *Context) ShouldBindXML(obj interface{}) error {
+	return c.ShouldBindWith(obj, BindingXML)
+}
+
+// ShouldBindQuery decodes the url query into obj, the fields are matched with the form tag.
+func (c *Context) ShouldBindQuery(obj interface{}) error {
+	return c.ShouldBindWith(obj, BindingQuery)
+}
+
+// ShouldBindHeader decodes the request headers into obj, the fields are matched with the header tag.
+func (c *Context) ShouldBindHeader(obj interface{}) error {
+	return c.ShouldBindWith(obj, BindingHeader)
+}
+
+// ShouldBindURI decodes the route params into obj, the fields are matched with the uri tag:
+//
+//	type UserURI struct {
+//		ID int `uri:"id"`
+//	}
+func (c *Context) ShouldBindURI(obj interface{}) error {
+	values := make(map[string][]string, len(c.Params))
+	for _, p := range c.Params {
+		values[p.Key] = append(values[p.Key], p.Value)
+	}
+
+	if err := mapValuesByTag(obj, values, "uri"); err != nil {
+		return err
+	}
+
+	return Validate(obj)
+}
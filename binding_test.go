@@ -0,0 +1,175 @@
+package slim
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindUser struct {
+	Name  string   `form:"name" json:"name" xml:"name"`
+	Age   int      `form:"age" json:"age" xml:"age"`
+	Tags  []string `form:"tag" json:"tags" xml:"tag"`
+	Admin *bool    `form:"admin" json:"admin" xml:"admin"`
+}
+
+func TestShouldBind(t *testing.T) {
+	tests := []struct {
+		method      string
+		contentType string
+		url         string
+		body        string
+	}{
+		{http.MethodPost, MIMEJSON, "/", `{"name":"bob","age":42,"tags":["a","b"],"admin":true}`},
+		{http.MethodPost, MIMEJSON + "; charset=utf-8", "/", `{"name":"bob","age":42,"tags":["a","b"],"admin":true}`},
+		{http.MethodPut, MIMEXML, "/", `<user><name>bob</name><age>42</age><tag>a</tag><tag>b</tag><admin>true</admin></user>`},
+		{http.MethodPost, MIMEPOSTForm, "/?age=42", "name=bob&tag=a&tag=b&admin=1"},
+		{http.MethodGet, "", "/?name=bob&age=42&tag=a&tag=b&admin=true", ""},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", tt.contentType)
+		c := &Context{Request: req, Method: req.Method}
+
+		var user bindUser
+		if err := c.ShouldBind(&user); err != nil {
+			t.Fatalf("%s %s: %v", tt.method, tt.contentType, err)
+		}
+
+		if user.Name != "bob" || user.Age != 42 || fmt.Sprint(user.Tags) != "[a b]" || user.Admin == nil || !*user.Admin {
+			t.Fatalf("%s %s: unexpected user %+v", tt.method, tt.contentType, user)
+		}
+	}
+}
+
+func TestBindError(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/?age=old", strings.NewReader(""))
+	w := httptest.NewRecorder()
+	c := &Context{Request: req, Method: req.Method, Writer: w}
+
+	var user bindUser
+	if err := c.Bind(&user); err == nil || !strings.Contains(err.Error(), "age") {
+		t.Fatalf("expected an error about age, got %v", err)
+	}
+
+	if w.Code != http.StatusBadRequest || !c.IsAborted() || len(c.Errors) != 1 || c.Errors[0].Type != ErrorTypeBind {
+		t.Fatalf("Bind should abort with 400 and record the error, got %d %v", w.Code, c.Errors)
+	}
+
+	if err := c.ShouldBindJSON(&user); err == nil {
+		t.Fatal("an empty JSON body should fail")
+	}
+
+	if err := c.ShouldBindQuery(user); err == nil {
+		t.Fatal("binding into a struct value should fail")
+	}
+}
+
+func TestShouldBindQueryURIHeader(t *testing.T) {
+	type request struct {
+		ID        int           `uri:"id"`
+		Slug      string        `uri:"slug"`
+		Page      int           `form:"page,default=1"`
+		Size      uint8         `form:"size,default=20"`
+		Timeout   time.Duration `form:"timeout"`
+		Since     time.Time     `form:"since" time_format:"2006-01-02"`
+		Until     time.Time     `form:"until" time_format:"unix"`
+		Ignored   string        `form:"-"`
+		RequestID string        `header:"x-request-id"`
+		Accept    []string      `header:"Accept"`
+	}
+
+	engine := New()
+	var got request
+	var errs []error
+	engine.GET("/posts/:id<int>/:slug", func(c *Context) {
+		errs = append(errs, c.ShouldBindURI(&got), c.ShouldBindQuery(&got), c.ShouldBindHeader(&got))
+	})
+
+	req := httptest.NewRequest(http.MethodGet,
+		"/posts/7/hello-world?size=5&timeout=2s&since=2021-03-04&until=1600000000&Ignored=x", nil)
+	req.Header.Set("X-Request-Id", "abc")
+	req.Header.Add("Accept", "text/html")
+	req.Header.Add("Accept", "application/json")
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+
+	if fmt.Sprint(errs) != "[<nil> <nil> <nil>]" {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+
+	expected := request{
+		ID:        7,
+		Slug:      "hello-world",
+		Page:      1,
+		Size:      5,
+		Timeout:   2 * time.Second,
+		Since:     time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC),
+		Until:     time.Unix(1600000000, 0),
+		RequestID: "abc",
+		Accept:    []string{"text/html", "application/json"},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Fatalf("expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestShouldBindMultipart(t *testing.T) {
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	mw.WriteField("title", "report")
+	for _, name := range []string{"a.txt", "b.txt"} {
+		fw, _ := mw.CreateFormFile("attachments", name)
+		fw.Write([]byte(name))
+	}
+	mw.Close()
+
+	var form struct {
+		Title       string                  `form:"title"`
+		Attachment  *multipart.FileHeader   `form:"attachments"`
+		Attachments []*multipart.FileHeader `form:"attachments"`
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	c := &Context{Request: req, Method: req.Method}
+	if err := c.ShouldBind(&form); err != nil {
+		t.Fatal(err)
+	}
+
+	if form.Title != "report" || form.Attachment == nil || form.Attachment.Filename != "a.txt" || len(form.Attachments) != 2 {
+		t.Fatalf("unexpected form %+v", form)
+	}
+}
+
+type csvBinding struct{}
+
+func (csvBinding) Name() string {
+	return "csv"
+}
+
+func (csvBinding) Bind(req *http.Request, obj interface{}) error {
+	buf := new(bytes.Buffer)
+	buf.ReadFrom(req.Body)
+	fields := strings.Split(strings.TrimSpace(buf.String()), ",")
+	return mapValuesByTag(obj, map[string][]string{"name": fields[:1], "tag": fields[1:]}, "form")
+}
+
+func TestRegisterBinding(t *testing.T) {
+	RegisterBinding("Text/CSV", csvBinding{})
+	defer delete(bindings, "text/csv")
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("bob,a,b\n"))
+	req.Header.Set("Content-Type", "text/csv")
+	c := &Context{Request: req, Method: req.Method}
+
+	var user bindUser
+	if err := c.ShouldBind(&user); err != nil || user.Name != "bob" || fmt.Sprint(user.Tags) != "[a b]" {
+		t.Fatalf("unexpected user %+v %v", user, err)
+	}
+}
@@ -0,0 +1,174 @@
+package slim
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// constraint restricts the values matched by a route param, e.g. :id<int>.
+type constraint struct {
+	expr  string            // as written in the pattern, without angle brackets
+	match func(string) bool // reports whether a param value satisfies the constraint
+}
+
+// paramTypes are the named param constraints,
+// any other constraint is used as a regular expression matching the whole value.
+var paramTypes = map[string]func(string) bool{
+	"int":   isInt,
+	"uint":  isUint,
+	"alpha": isAlpha,
+	"alnum": isAlnum,
+	"uuid":  isUUID,
+}
+
+// overlappingTypes lists for each named constraint the other named ones that
+// some values satisfy as well, e.g. 42 is an int, a uint and an alnum.
+var overlappingTypes = map[string][]string{
+	"int":   {"uint", "alnum"},
+	"uint":  {"int", "alnum"},
+	"alpha": {"alnum"},
+	"alnum": {"int", "uint", "alpha"},
+}
+
+// newConstraint returns the constraint for expr, or nil if expr is empty.
+func newConstraint(expr string) (*constraint, error) {
+	if expr == "" {
+		return nil, nil
+	}
+
+	if fn, ok := paramTypes[expr]; ok {
+		return &constraint{expr: expr, match: fn}, nil
+	}
+
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid param constraint <%s>: %s", expr, err)
+	}
+
+	return &constraint{expr: expr, match: re.MatchString}, nil
+}
+
+// overlaps reports whether c and other are named types that a value may satisfy both,
+// it is false if either is a regular expression since they cannot be compared.
+func (c *constraint) overlaps(other *constraint) bool {
+	if c == nil || other == nil {
+		return false
+	}
+
+	for _, expr := range overlappingTypes[c.expr] {
+		if expr == other.expr {
+			return true
+		}
+	}
+
+	return false
+}
+
+// String returns the constraint expression, "" for a nil constraint.
+func (c *constraint) String() string {
+	if c == nil {
+		return ""
+	}
+
+	return c.expr
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isInt reports whether s is a decimal integer that fits in an int64.
+func isInt(s string) bool {
+	digits := s
+	if digits != "" && digits[0] == '-' {
+		digits = digits[1:]
+	}
+
+	if !isDigits(digits) {
+		return false
+	}
+
+	if len(digits) < 19 {
+		return true
+	}
+
+	_, err := strconv.ParseInt(s, 10, 64)
+	return err == nil
+}
+
+// isUint reports whether s is a decimal integer that fits in an uint64.
+func isUint(s string) bool {
+	if !isDigits(s) {
+		return false
+	}
+
+	if len(s) < 20 {
+		return true
+	}
+
+	_, err := strconv.ParseUint(s, 10, 64)
+	return err == nil
+}
+
+func isAlpha(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		if c := s[i] | 0x20; c < 'a' || c > 'z' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isAlnum(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c|0x20 < 'a' || c|0x20 > 'z') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isUUID reports whether s is formatted like 123e4567-e89b-12d3-a456-426614174000.
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if (c < '0' || c > '9') && (c|0x20 < 'a' || c|0x20 > 'f') {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -1,10 +1,11 @@
 package slim
 
 import (
-	"encoding/json"
 	"fmt"
 	"math"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 )
 
@@ -22,7 +23,7 @@ type Context struct {
 	// request info
 	Path   string
 	Method string
-	Params map[string]string
+	Params Params // host params first, then the route params in order
 	// response info
 	StatusCode int
 
@@ -35,16 +36,64 @@ type Context struct {
 
 	// engine pointer
 	engine *Engine
+
+	// 匹配到的路由，未匹配时为 nil
+	route *route
+
+	// queryCache caches the query result from c.Request.URL.Query().
+	queryCache url.Values
+
+	// formCache caches c.Request.PostForm, which contains the parsed form data from POST, PATCH,
+	// or PUT body parameters.
+	formCache url.Values
 }
 
-func newContext(w http.ResponseWriter, req *http.Request) *Context {
-	return &Context{
-		Path:    req.URL.Path,
-		Method:  req.Method,
-		Request: req,
-		Writer:  w,
-		index:   -1,
-	}
+// reset prepares a pooled context for a new request, the Params capacity is kept.
+func (c *Context) reset(w http.ResponseWriter, req *http.Request) {
+	c.Writer = w
+	c.Request = req
+	c.Path = req.URL.Path
+	c.Method = req.Method
+	c.Params = c.Params[:0]
+	c.StatusCode = 0
+	c.handlers = nil
+	c.index = -1
+	c.Errors = c.Errors[:0]
+	c.route = nil
+	c.queryCache = nil
+	c.formCache = nil
+}
+
+// Copy returns a copy of the current context that can be safely used outside the request's scope.
+// The context of a request is reused by the engine once the handlers return,
+// so it must be copied before being passed to a goroutine.
+// The copy cannot write the response, its Writer panics if used.
+func (c *Context) Copy() *Context {
+	cp := *c
+	cp.Writer = copyWriter{}
+	cp.Params = append(Params(nil), c.Params...)
+	cp.Errors = append(errorMsgs(nil), c.Errors...)
+	cp.handlers = nil
+	cp.index = abortIndex
+	return &cp
+}
+
+// copyWriter is the Writer of a context returned by Copy, the response belongs to
+// the request that may already be over so any use panics with a clear message.
+type copyWriter struct{}
+
+const copyWriterMessage = "slim: cannot write the response from a context returned by Context.Copy"
+
+func (copyWriter) Header() http.Header {
+	panic(copyWriterMessage)
+}
+
+func (copyWriter) Write([]byte) (int, error) {
+	panic(copyWriterMessage)
+}
+
+func (copyWriter) WriteHeader(int) {
+	panic(copyWriterMessage)
 }
 
 // Next 执行下一个中间件
@@ -130,6 +179,21 @@ func (c *Context) HandlerNames() []string {
 	return hn
 }
 
+// Route returns the method, pattern and metadata of the route matched by the request,
+// or nil for NoRoute, NoMethod and automatic OPTIONS responses.
+// Middleware can use it to make decisions without hard-coding paths:
+//
+//	if perm := c.Route().GetString("permission"); perm != "" && !allowed(c, perm) {
+//		c.AbortWithStatus(http.StatusForbidden)
+//	}
+func (c *Context) Route() *RouteMeta {
+	if c.route == nil {
+		return nil
+	}
+
+	return c.route.meta
+}
+
 // Handler returns the main handler.
 func (c *Context) Handler() HandlerFunc {
 	return c.handlers.Last()
@@ -143,8 +207,27 @@ func (c *Context) Fail(code int, message string) {
 
 // Param 获取路由上的参数
 func (c *Context) Param(key string) string {
-	value, _ := c.Params[key]
-	return value
+	return c.Params.ByName(key)
+}
+
+// ParamInt returns the route param as an int, or an error if the param is missing
+// or is not a decimal integer that fits in an int. The router checks that a param
+// declared as :key<int> fits in an int64, so it is out of range on 32-bit platforms
+// for values beyond 32 bits, use ParamInt64 for those.
+func (c *Context) ParamInt(key string) (int, error) {
+	return strconv.Atoi(c.Param(key))
+}
+
+// ParamInt64 returns the route param as an int64, see ParamInt.
+// For a param declared as :key<int> only a missing param can fail.
+func (c *Context) ParamInt64(key string) (int64, error) {
+	return strconv.ParseInt(c.Param(key), 10, 64)
+}
+
+// ParamUint64 returns the route param as an uint64.
+// For a param declared as :key<uint> only a missing param can fail.
+func (c *Context) ParamUint64(key string) (uint64, error) {
+	return strconv.ParseUint(c.Param(key), 10, 64)
 }
 
 // Status set http status
@@ -158,21 +241,15 @@ func (c *Context) SetHeader(key string, value string) {
 	c.Writer.Header().Set(key, value)
 }
 
-// String write string to body
+// String write string to body, format is always formatted with values, see fmt.Sprintf,
+// c.String(200, "100%%") writes 100%.
 func (c *Context) String(code int, format string, values ...interface{}) {
-	c.SetHeader("Content-Type", "text/plain")
-	c.Status(code)
-	c.Writer.Write([]byte(fmt.Sprintf(format, values...)))
+	c.Render(code, RenderText{Format: fmt.Sprintf(format, values...)})
 }
 
 // JSON write json to body
 func (c *Context) JSON(code int, obj interface{}) {
-	c.SetHeader("Content-Type", "application/json")
-	c.Status(code)
-	encoder := json.NewEncoder(c.Writer)
-	if err := encoder.Encode(obj); err != nil {
-		http.Error(c.Writer, err.Error(), http.StatusInternalServerError)
-	}
+	c.Render(code, RenderJSON{Data: obj})
 }
 
 // ApiSuccess write json to body
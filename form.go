@@ -0,0 +1,172 @@
+package slim
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// defaultMultipartMemory is the default value of Engine.MaxMultipartMemory.
+const defaultMultipartMemory = 32 << 20 // 32 MB
+
+// initFormCache parses the body of the request once per request,
+// for both url-encoded and multipart forms.
+func (c *Context) initFormCache() {
+	if c.formCache != nil {
+		return
+	}
+
+	c.formCache = url.Values{}
+	if c.Request == nil {
+		return
+	}
+
+	if err := c.parseMultipartForm(); err != nil && err != http.ErrNotMultipart {
+		c.Error(fmt.Errorf("slim: cannot parse the form: %v", err)).SetType(ErrorTypeBind)
+	}
+
+	if c.Request.PostForm != nil {
+		c.formCache = c.Request.PostForm
+	}
+}
+
+// parseMultipartForm parses the request body, keeping up to Engine.MaxMultipartMemory
+// bytes of the uploaded files in memory.
+func (c *Context) parseMultipartForm() error {
+	if c.Request.MultipartForm != nil {
+		return nil
+	}
+
+	maxMemory := int64(defaultMultipartMemory)
+	if c.engine != nil {
+		maxMemory = c.engine.MaxMultipartMemory
+	}
+
+	return c.Request.ParseMultipartForm(maxMemory)
+}
+
+// PostForm returns the first value of the url-encoded or multipart form key, or "" if it does not exist.
+func (c *Context) PostForm(key string) string {
+	value, _ := c.GetPostForm(key)
+	return value
+}
+
+// DefaultPostForm returns the first value of the form key, or defaultValue if it does not exist.
+func (c *Context) DefaultPostForm(key, defaultValue string) string {
+	if value, ok := c.GetPostForm(key); ok {
+		return value
+	}
+
+	return defaultValue
+}
+
+// GetPostForm is like PostForm, it also reports whether the key exists, even with an empty value.
+func (c *Context) GetPostForm(key string) (string, bool) {
+	if values, ok := c.GetPostFormArray(key); ok {
+		return values[0], true
+	}
+
+	return "", false
+}
+
+// PostFormArray returns all the values of the form key.
+func (c *Context) PostFormArray(key string) []string {
+	values, _ := c.GetPostFormArray(key)
+	return values
+}
+
+// GetPostFormArray is like PostFormArray, it also reports whether the key has at least one value.
+func (c *Context) GetPostFormArray(key string) ([]string, bool) {
+	c.initFormCache()
+	values, ok := c.formCache[key]
+	return values, ok && len(values) > 0
+}
+
+// PostFormMap returns the form keys like key[name] as a map indexed by name, see QueryMap.
+func (c *Context) PostFormMap(key string) map[string]string {
+	dict, _ := c.GetPostFormMap(key)
+	return dict
+}
+
+// GetPostFormMap is like PostFormMap, it also reports whether there is at least one such key.
+func (c *Context) GetPostFormMap(key string) (map[string]string, bool) {
+	c.initFormCache()
+	return mapValues(c.formCache, key)
+}
+
+// FormFile returns the first file uploaded with the multipart form key.
+func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	if err := c.parseMultipartForm(); err != nil {
+		return nil, err
+	}
+
+	f, fh, err := c.Request.FormFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	f.Close()
+	return fh, nil
+}
+
+// MultipartForm returns the parsed multipart form, including the uploaded files.
+// The temporary files are removed once the request is handled.
+func (c *Context) MultipartForm() (*multipart.Form, error) {
+	err := c.parseMultipartForm()
+	return c.Request.MultipartForm, err
+}
+
+// SaveUploadedFile saves the uploaded file to dst.
+// If dst is an existing directory or ends with a slash, the file is saved inside it
+// under the base name of the uploaded filename, so that a filename like ../../etc/passwd
+// cannot write outside of dst. Never build dst from the uploaded filename yourself.
+func (c *Context) SaveUploadedFile(file *multipart.FileHeader, dst string) error {
+	if info, err := os.Stat(dst); strings.HasSuffix(dst, "/") || (err == nil && info.IsDir()) {
+		name, err := uploadedFilename(file.Filename)
+		if err != nil {
+			return err
+		}
+
+		dst = filepath.Join(dst, name)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, src)
+	return err
+}
+
+// removeMultipartForm removes the temporary files of the form parsed into req,
+// a copy of the original request, unless the form has been parsed into the original.
+func removeMultipartForm(req *http.Request, original *http.Request) {
+	if req.MultipartForm != nil && req.MultipartForm != original.MultipartForm {
+		req.MultipartForm.RemoveAll()
+	}
+}
+
+// uploadedFilename returns the base name of an uploaded filename,
+// browsers on Windows may send a full path with backslashes.
+func uploadedFilename(filename string) (string, error) {
+	name := path.Base(strings.ReplaceAll(filename, "\\", "/"))
+	if name == "." || name == ".." || name == "/" || strings.ContainsRune(name, 0) {
+		return "", fmt.Errorf("slim: invalid uploaded filename %q", filename)
+	}
+
+	return name, nil
+}
@@ -0,0 +1,147 @@
+package slim
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// hostRouter holds the routes bound to a host pattern by Engine.Host.
+type hostRouter struct {
+	pattern string
+	labels  []string // labels of the pattern, params start with ':', the static ones are lower-cased
+	router  *router
+}
+
+// Host returns a router group whose routes only serve requests for the host pattern.
+// The pattern is a dot separated host name where a label starting with ':'
+// is a param exposed by Context.Param, e.g. ":tenant.example.com".
+// The port of the request host is ignored and the comparison is case-insensitive,
+// the param names keep their case, e.g. c.Param("tenantID") for ":tenantID.example.com".
+// A request whose host matches a pattern, in registration order, is routed with the
+// routes of that host only, any other request with the routes registered on the engine.
+// Calling Host again with the same pattern returns a group sharing the same routes.
+func (engine *Engine) Host(pattern string) *RouterGroup {
+	labels := strings.Split(pattern, ".")
+	for i, label := range labels {
+		if label == "" || label == ":" {
+			panic("slim: invalid host pattern '" + pattern + "'")
+		}
+
+		if label[0] != ':' {
+			labels[i] = strings.ToLower(label)
+		}
+	}
+
+	pattern = strings.Join(labels, ".")
+	var h *hostRouter
+	for _, hr := range engine.hosts {
+		if hr.pattern == pattern {
+			h = hr
+			break
+		}
+	}
+
+	if h == nil {
+		h = &hostRouter{pattern: pattern, labels: labels, router: newRouter()}
+		engine.hosts = append(engine.hosts, h)
+	}
+
+	group := &RouterGroup{
+		engine: engine,
+		parent: engine.RouterGroup,
+		host:   h,
+	}
+
+	group.rebuildOptionsHandlers()
+	engine.groups = append(engine.groups, group)
+	debugPrintf("engine host: %s", pattern)
+	return group
+}
+
+// match reports whether host matches the pattern of h and appends the host params to ps.
+func (h *hostRouter) match(host string, ps *Params) bool {
+	n := len(*ps)
+	for i, label := range h.labels {
+		end := strings.IndexByte(host, '.')
+		if end < 0 {
+			end = len(host)
+		}
+
+		if (i == len(h.labels)-1) != (end == len(host)) || end == 0 {
+			*ps = (*ps)[:n]
+			return false
+		}
+
+		value := host[:end]
+		if label[0] == ':' {
+			*ps = append(*ps, Param{Key: label[1:], Value: value})
+		} else if !strings.EqualFold(label, value) {
+			*ps = (*ps)[:n]
+			return false
+		}
+
+		if end < len(host) {
+			host = host[end+1:]
+		}
+	}
+
+	return true
+}
+
+// buildHost fills the params of the host pattern of h with the first params,
+// it returns the host and the number of params used.
+func (h *hostRouter) buildHost(params []interface{}) (string, int, error) {
+	labels := make([]string, len(h.labels))
+	n := 0
+	for i, label := range h.labels {
+		if label[0] != ':' {
+			labels[i] = label
+			continue
+		}
+
+		if n >= len(params) {
+			return "", 0, fmt.Errorf("slim: host '%s' expects more than %d params", h.pattern, len(params))
+		}
+
+		value := fmt.Sprint(params[n])
+		n++
+		if value == "" || strings.ContainsAny(value, "./\\:@?#% ") {
+			return "", 0, fmt.Errorf("slim: param '%s' of host '%s' is not a valid label: %q", label, h.pattern, value)
+		}
+
+		labels[i] = value
+	}
+
+	return strings.Join(labels, "."), n, nil
+}
+
+// routerForHost returns the router serving requests for host,
+// the host params are appended to ps for the routes bound to a host pattern.
+func (engine *Engine) routerForHost(host string, ps *Params) *router {
+	if len(engine.hosts) == 0 {
+		return engine.router
+	}
+
+	host = stripHostPort(host)
+	for _, h := range engine.hosts {
+		if h.match(host, ps) {
+			return h.router
+		}
+	}
+
+	return engine.router
+}
+
+// stripHostPort returns host without any port number.
+func stripHostPort(host string) string {
+	if !strings.Contains(host, ":") {
+		return host
+	}
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+
+	return host
+}
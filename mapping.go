@@ -0,0 +1,257 @@
+package slim
+
+import (
+	"encoding"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType        = reflect.TypeOf(time.Time{})
+	durationType    = reflect.TypeOf(time.Duration(0))
+	fileHeaderType  = reflect.TypeOf(&multipart.FileHeader{})
+	fileHeadersType = reflect.TypeOf([]*multipart.FileHeader{})
+	unmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// valueGetter returns the values of a struct field looked up by name.
+type valueGetter func(name string) ([]string, bool)
+
+// mapValuesByTag sets the fields of the struct pointed to by obj from values,
+// the fields are matched by the name given in the tag or else by their name.
+func mapValuesByTag(obj interface{}, values map[string][]string, tag string) error {
+	return mapByTag(obj, tag, func(name string) ([]string, bool) {
+		vs, ok := values[name]
+		return vs, ok
+	}, nil)
+}
+
+// mapHeader sets the fields of the struct pointed to by obj from the request headers,
+// the header tag is case-insensitive.
+func mapHeader(obj interface{}, header http.Header) error {
+	return mapByTag(obj, "header", func(name string) ([]string, bool) {
+		vs, ok := header[textproto.CanonicalMIMEHeaderKey(name)]
+		return vs, ok
+	}, nil)
+}
+
+// mapMultipart sets the fields of the struct pointed to by obj from a multipart form,
+// fields of type *multipart.FileHeader and []*multipart.FileHeader receive the uploaded files.
+func mapMultipart(obj interface{}, form *multipart.Form) error {
+	return mapByTag(obj, "form", func(name string) ([]string, bool) {
+		vs, ok := form.Value[name]
+		return vs, ok
+	}, form.File)
+}
+
+func mapByTag(obj interface{}, tag string, get valueGetter, files map[string][]*multipart.FileHeader) error {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("slim: cannot bind into %T, a pointer to a struct is expected", obj)
+	}
+
+	return mapStruct(v.Elem(), tag, get, files)
+}
+
+// mapStruct sets the fields of v, nested structs without tag are mapped recursively.
+// A tag like `form:"page,default=1"` gives the value used when the request has none.
+func mapStruct(v reflect.Value, tag string, get valueGetter, files map[string][]*multipart.FileHeader) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, defaultValue, hasDefault := parseBindingTag(sf.Tag.Get(tag))
+		if name == "-" {
+			continue
+		}
+
+		field := v.Field(i)
+		if name == "" && sf.Type.Kind() == reflect.Struct && sf.Type != timeType {
+			if sf.PkgPath == "" || sf.Anonymous {
+				if err := mapStruct(field, tag, get, files); err != nil {
+					return err
+				}
+			}
+
+			continue
+		}
+
+		if sf.PkgPath != "" {
+			continue // unexported
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+
+		if sf.Type == fileHeaderType || sf.Type == fileHeadersType {
+			if fhs := files[name]; len(fhs) > 0 {
+				if sf.Type == fileHeaderType {
+					field.Set(reflect.ValueOf(fhs[0]))
+				} else {
+					field.Set(reflect.ValueOf(fhs))
+				}
+			}
+
+			continue
+		}
+
+		values, ok := get(name)
+		if !ok || len(values) == 0 {
+			if !hasDefault {
+				continue
+			}
+
+			values = []string{defaultValue}
+		}
+
+		if err := setField(field, sf, values); err != nil {
+			return fmt.Errorf("slim: cannot bind %s '%s' into %s: %v", tag, name, sf.Type, err)
+		}
+	}
+
+	return nil
+}
+
+// parseBindingTag splits a tag like "page,default=1".
+func parseBindingTag(tag string) (name string, defaultValue string, hasDefault bool) {
+	parts := strings.Split(tag, ",")
+	for _, option := range parts[1:] {
+		if strings.HasPrefix(option, "default=") {
+			defaultValue, hasDefault = option[len("default="):], true
+		}
+	}
+
+	return parts[0], defaultValue, hasDefault
+}
+
+// setField sets field from values, a slice field receives all the values.
+func setField(field reflect.Value, sf reflect.StructField, values []string) error {
+	if field.Kind() == reflect.Slice && !field.Addr().Type().Implements(unmarshalerType) {
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, s := range values {
+			if err := setValue(slice.Index(i), sf, s); err != nil {
+				return err
+			}
+		}
+
+		field.Set(slice)
+		return nil
+	}
+
+	return setValue(field, sf, values[0])
+}
+
+// setValue parses s into v according to its type.
+func setValue(v reflect.Value, sf reflect.StructField, s string) error {
+	if v.Kind() == reflect.Ptr {
+		elem := reflect.New(v.Type().Elem())
+		if err := setValue(elem.Elem(), sf, s); err != nil {
+			return err
+		}
+
+		v.Set(elem)
+		return nil
+	}
+
+	switch v.Type() {
+	case durationType:
+		if s == "" {
+			return nil
+		}
+
+		d, err := time.ParseDuration(s)
+		if err == nil {
+			v.SetInt(int64(d))
+		}
+
+		return err
+	case timeType:
+		return setTime(v, sf, s)
+	}
+
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+
+	if v.Kind() == reflect.String {
+		v.SetString(s)
+		return nil
+	}
+
+	if s == "" {
+		return nil // the zero value
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err == nil {
+			v.SetBool(b)
+		}
+
+		return err
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err == nil {
+			v.SetInt(i)
+		}
+
+		return err
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err == nil {
+			v.SetUint(u)
+		}
+
+		return err
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err == nil {
+			v.SetFloat(f)
+		}
+
+		return err
+	}
+
+	return fmt.Errorf("unsupported type %s", v.Type())
+}
+
+// setTime parses s with the layout of the time_format tag, RFC 3339 by default,
+// "unix" and "unixnano" parse a number of seconds or nanoseconds.
+func setTime(v reflect.Value, sf reflect.StructField, s string) error {
+	if s == "" {
+		return nil
+	}
+
+	layout := sf.Tag.Get("time_format")
+	switch layout {
+	case "":
+		layout = time.RFC3339
+	case "unix", "unixnano":
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		t := time.Unix(n, 0)
+		if layout == "unixnano" {
+			t = time.Unix(0, n)
+		}
+
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	t, err := time.Parse(layout, s)
+	if err == nil {
+		v.Set(reflect.ValueOf(t))
+	}
+
+	return err
+}
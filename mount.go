@@ -0,0 +1,109 @@
+package slim
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// mountParam is the name of the catch-all param of mounted handlers.
+const mountParam = "mountpath"
+
+// mountMethods are the methods served by mounted handlers, CONNECT and TRACE are left out.
+var mountMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+}
+
+// mount records an Engine mounted by RouterGroup.Mount for route introspection.
+type mount struct {
+	prefix      string
+	host        string
+	middlewares HandlersChain // middleware of the group the engine is mounted on
+	engine      *Engine
+}
+
+// Mount serves every request below prefix with handler, for the methods in mountMethods.
+// The prefix is stripped from Request.URL.Path before calling handler and the
+// middleware of the group runs before it.
+// If handler is an *Engine, its routes are listed by Engine.Routes under prefix.
+// Naming the returned route names the prefix, so that Engine.URL returns it,
+// or the /*mountpath catch-all taking the rest of the path for a mount at the root.
+func (group *RouterGroup) Mount(prefix string, handler http.Handler) *Route {
+	absolutePath := strings.TrimSuffix(group.calculateAbsolutePath(prefix), "/")
+	mountHandler := func(c *Context) {
+		req := new(http.Request)
+		*req = *c.Request
+		req.URL = new(url.URL)
+		*req.URL = *c.Request.URL
+		req.URL.Path = "/" + c.Param(mountParam)
+		req.URL.RawPath = ""
+		handler.ServeHTTP(c.Writer, req)
+		removeMultipartForm(req, c.Request)
+	}
+
+	relativePath := strings.TrimSuffix(prefix, "/")
+	paths := []string{relativePath + "/*" + mountParam}
+	if absolutePath != "" {
+		paths = append([]string{relativePath}, paths...) // the first route is the one named by Route.Name
+	}
+
+	r := &Route{engine: group.engine, router: group.router()}
+	for _, p := range paths {
+		for _, method := range mountMethods {
+			r.routes = append(r.routes, group.handle(method, p, HandlersChain{mountHandler}).routes...)
+		}
+	}
+
+	if child, ok := handler.(*Engine); ok {
+		m := &mount{
+			prefix:      absolutePath,
+			middlewares: group.combineHandlers(nil),
+			engine:      child,
+		}
+
+		if group.host != nil {
+			m.host = group.host.pattern
+		}
+
+		group.engine.mounts = append(group.engine.mounts, m)
+	}
+
+	return r
+}
+
+// isMountRoute reports whether route serves an engine mounted by RouterGroup.Mount.
+func (engine *Engine) isMountRoute(route RouteInfo) bool {
+	for _, m := range engine.mounts {
+		if route.Host == m.host && (route.Path == m.prefix || route.Path == m.prefix+"/*"+mountParam) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// routesInfo returns the routes of the mounted engine as seen from the parent engine.
+func (m *mount) routesInfo() RoutesInfo {
+	routes := m.engine.Routes()
+	for i := range routes {
+		routes[i].Path = m.prefix + routes[i].Path
+		if routes[i].Host == "" {
+			routes[i].Host = m.host
+		}
+
+		middlewares := make([]string, 0, len(m.middlewares)+len(routes[i].Middlewares))
+		for _, h := range m.middlewares {
+			middlewares = append(middlewares, nameOfFunction(h))
+		}
+
+		routes[i].Middlewares = append(middlewares, routes[i].Middlewares...)
+	}
+
+	return routes
+}
@@ -10,3 +10,10 @@ func NoRoute() HandlerFunc {
 		c.String(http.StatusNotFound, "404 not found: %s\n", c.Path)
 	}
 }
+
+// NoMethod method not allowed HandlersChain
+func NoMethod() HandlerFunc {
+	return func(c *Context) {
+		c.String(http.StatusMethodNotAllowed, "405 method not allowed: %s %s\n", c.Method, c.Path)
+	}
+}
@@ -1,54 +1,656 @@
 package slim
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 )
 
-// node 使用 Trie 树实现动态路由(dynamic route)解析
+// Param is a single URL parameter, consisting of a key and a value.
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Params is a Param-slice, as returned by the router.
+// The slice is ordered, the first URL parameter is also the first slice value.
+type Params []Param
+
+// Get returns the value of the first Param which key matches the given name.
+// If no matching Param is found, an empty string is returned and exists is false.
+func (ps Params) Get(name string) (string, bool) {
+	for _, p := range ps {
+		if p.Key == name {
+			return p.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// ByName returns the value of the first Param which key matches the given name.
+// If no matching Param is found, an empty string is returned.
+func (ps Params) ByName(name string) string {
+	value, _ := ps.Get(name)
+	return value
+}
+
+// nodeType 节点类型
+type nodeType uint8
+
+const (
+	static   nodeType = iota // 静态节点，例如 /users/
+	param                    // 参数节点，例如 :lang
+	catchAll                 // 通配节点，例如 *filepath
+)
+
+// node 使用压缩前缀树(radix tree)实现动态路由(dynamic route)解析
+// 静态子节点按照 priority 排序，查找时优先级为 static > param > catchAll
 type node struct {
-	pattern  string  // 待匹配路由，例如 /p/:lang
-	part     string  // 路由中的一部分，例如 :lang
-	children []*node // 子节点，例如 [doc, tutorial, intro]
-	isWild   bool    // 是否精确匹配，part 含有 : 或 * 时为true
+	path     string      // 静态节点为压缩后的路径片段，参数节点为 :name，通配节点为 *name
+	key      string      // 参数名称，仅 param/catchAll 节点有效
+	nType    nodeType    // 节点类型
+	indices  string      // 静态子节点 path 的首字节，与 children 一一对应
+	children []*node     // 静态子节点，按照 priority 从高到低排序
+	params   []*node     // 参数子节点
+	wildcard *node       // 通配子节点
+	priority uint32      // 子树中注册的路由数量
+	cons     *constraint // 参数约束，例如 :id<int>，仅 param 节点有效
+	pattern  string      // 待匹配路由，例如 /p/:lang，仅叶子节点有效
+	route    *route      // 路由信息，仅叶子节点有效
 }
 
 // String return node string
 func (n *node) String() string {
-	return fmt.Sprintf("node{pattern=%s, part=%s, isWild=%t}", n.pattern, n.part, n.isWild)
+	return fmt.Sprintf("node{pattern=%s, path=%s, isWild=%t}", n.pattern, n.path, n.nType != static)
+}
+
+// patternToken is a piece of a route pattern: static text, a :param or a *catchAll.
+type patternToken struct {
+	nType      nodeType
+	text       string // static text or wildcard name
+	constraint string // param constraint without the angle brackets
+}
+
+// parsePatternTokens splits pattern into static, param and catch-all tokens.
+// A param starts with ':' anywhere in a segment and its name consists of
+// letters, digits and '_' and starts with a letter or '_', so it can be surrounded
+// by static text like in /v:version/users or /files/:name.:ext. Two params must be
+// separated by static text. A literal ':' is escaped as '\:', e.g. /api/v1\:batchGet.
+// A param may be followed by a constraint in angle brackets which is either
+// a named type like :id<int> or a regular expression like :slug<[a-z-]+>.
+// A segment starting with '*' is a catch-all and must be the last segment of the pattern.
+func parsePatternTokens(pattern string) ([]patternToken, error) {
+	var tokens []patternToken
+	var text string // static text before pattern[start:] with the escapes removed
+	start := 0
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		if c == '\\' && i+1 < len(pattern) && pattern[i+1] == ':' {
+			text += pattern[start:i]
+			start = i + 1
+			i++
+			continue
+		}
+
+		if c == '*' && i > 0 && pattern[i-1] == '/' {
+			if strings.IndexByte(pattern[i:], '/') >= 0 {
+				return nil, errors.New("catch-all is only allowed at the end of the path")
+			}
+
+			tokens = append(tokens, patternToken{nType: static, text: text + pattern[start:i]})
+			tokens = append(tokens, patternToken{nType: catchAll, text: pattern[i+1:]})
+			return tokens, nil
+		}
+
+		if c != ':' {
+			continue
+		}
+
+		if text += pattern[start:i]; text != "" {
+			tokens = append(tokens, patternToken{nType: static, text: text})
+			text = ""
+		} else if len(tokens) > 0 {
+			return nil, errors.New("params must be separated by static text")
+		}
+
+		j := i + 1
+		for j < len(pattern) && isParamNameChar(pattern[j]) {
+			j++
+		}
+
+		if j == i+1 {
+			return nil, errors.New("wildcards must be named with a non-empty name")
+		}
+
+		if name := pattern[i+1]; name >= '0' && name <= '9' {
+			return nil, errors.New("param name ':" + pattern[i+1:j] + "' must start with a letter or '_', escape a literal ':' as '\\:'")
+		}
+
+		token := patternToken{nType: param, text: pattern[i+1 : j]}
+		if j < len(pattern) && pattern[j] == '<' {
+			end := constraintEnd(pattern, j)
+			if end < 0 {
+				return nil, errors.New("param constraint of ':" + token.text + "' is not closed")
+			}
+
+			token.constraint = pattern[j+1 : end-1]
+			if token.constraint == "" {
+				return nil, errors.New("param constraint of ':" + token.text + "' is empty")
+			}
+
+			j = end
+		}
+
+		tokens = append(tokens, token)
+		start = j
+		i = j - 1
+	}
+
+	if text += pattern[start:]; text != "" {
+		tokens = append(tokens, patternToken{nType: static, text: text})
+	}
+
+	return tokens, nil
+}
+
+func isParamNameChar(c byte) bool {
+	return c == '_' || (c >= '0' && c <= '9') || (c|0x20 >= 'a' && c|0x20 <= 'z')
+}
+
+// constraintEnd returns the index after the '>' closing the '<' at pattern[i],
+// or -1 if it is not closed. Nested angle brackets and escaped characters are skipped.
+func constraintEnd(pattern string, i int) int {
+	depth := 0
+	for ; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '<':
+			depth++
+		case '>':
+			if depth--; depth == 0 {
+				return i + 1
+			}
+		}
+	}
+
+	return -1
+}
+
+// clone returns a shallow copy of n that owns its children slices,
+// the children themselves are still shared.
+func (n *node) clone() *node {
+	c := *n
+	c.children = append([]*node(nil), n.children...)
+	c.params = append([]*node(nil), n.params...)
+	return &c
+}
+
+// insert 插入路由规则，返回对应的叶子节点
+// 与已有路由冲突时返回 *RouteConflictError
+// n 不能被查找方共享(例如 clone 的结果)，insert 会复制沿途修改的节点，
+// 所以原有的树在插入过程中和插入之后都保持不变
+func (n *node) insert(pattern string, tokens []patternToken, r *route) (*node, error) {
+	var err error
+	n.priority++
+	for _, token := range tokens {
+		switch token.nType {
+		case static:
+			n = n.insertStatic(token.text)
+		case param:
+			n, err = n.insertParam(token)
+		case catchAll:
+			n, err = n.insertCatchAll(token.text)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if n.route != nil {
+		return nil, &RouteConflictError{Existing: n.pattern, Reason: "route is already registered"}
+	}
+
+	n.pattern = pattern
+	n.route = r
+	return n, nil
+}
+
+// insertStatic inserts the static text below n, splitting existing nodes on
+// their longest common prefix, and returns the node that ends with path.
+func (n *node) insertStatic(path string) *node {
+	for path != "" {
+		i := n.staticChild(path[0])
+		if i < 0 {
+			child := &node{path: path, nType: static}
+			n.indices += string([]byte{path[0]})
+			n.children = append(n.children, child)
+			n.incrementChildPrio(len(n.children) - 1)
+			return child
+		}
+
+		n.children[i] = n.children[i].clone()
+		i = n.incrementChildPrio(i)
+		child := n.children[i]
+		l := longestCommonPrefix(path, child.path)
+		if l < len(child.path) {
+			child.split(l)
+		}
+
+		path = path[l:]
+		n = child
+	}
+
+	return n
+}
+
+// insertParam returns the param child of n for token, creating it if needed.
+// Params with different constraints may share a position, they are tried
+// in registration order and the unconstrained one, if any, is tried last.
+// Named types that overlap, like <int> and <uint>, conflict so that the match
+// does not depend on the registration order, the regular expressions are not checked.
+func (n *node) insertParam(token patternToken) (*node, error) {
+	for i, child := range n.params {
+		if child.cons.String() != token.constraint {
+			continue
+		}
+
+		if child.key != token.text {
+			return nil, &RouteConflictError{
+				Existing: child.firstPattern(),
+				Reason: "wildcard '" + paramPath(token.text, token.constraint) +
+					"' conflicts with existing wildcard '" + child.path + "'",
+			}
+		}
+
+		child = child.clone()
+		child.priority++
+		n.params[i] = child
+		return child, nil
+	}
+
+	cons, err := newConstraint(token.constraint)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, child := range n.params {
+		if child.cons.overlaps(cons) {
+			return nil, &RouteConflictError{
+				Existing: child.firstPattern(),
+				Reason: "wildcard '" + paramPath(token.text, token.constraint) +
+					"' overlaps with existing wildcard '" + child.path + "'",
+			}
+		}
+	}
+
+	child := &node{
+		path:     paramPath(token.text, token.constraint),
+		key:      token.text,
+		nType:    param,
+		priority: 1,
+		cons:     cons,
+	}
+
+	i := len(n.params)
+	if cons != nil && i > 0 && n.params[i-1].cons == nil {
+		i--
+	}
+
+	n.params = append(n.params, nil)
+	copy(n.params[i+1:], n.params[i:])
+	n.params[i] = child
+	return child, nil
+}
+
+// paramPath returns the param as written in a pattern, e.g. :id<int>.
+func paramPath(key string, constraint string) string {
+	if constraint == "" {
+		return ":" + key
+	}
+
+	return ":" + key + "<" + constraint + ">"
+}
+
+func (n *node) insertCatchAll(key string) (*node, error) {
+	if child := n.wildcard; child != nil {
+		if child.key != key {
+			return nil, &RouteConflictError{
+				Existing: child.firstPattern(),
+				Reason:   "catch-all '*" + key + "' conflicts with existing catch-all '*" + child.key + "'",
+			}
+		}
+
+		child = child.clone()
+		child.priority++
+		n.wildcard = child
+		return child, nil
+	}
+
+	n.wildcard = &node{path: "*" + key, key: key, nType: catchAll, priority: 1}
+	return n.wildcard, nil
+}
+
+// update 找到 tokens 对应的路由所在的叶子节点，调用 fn 修改它，返回是否找到了该路由
+// 与 insert 一样，n 不能被查找方共享，沿途的节点(包括叶子节点)会被复制，
+// fn 删除路由后不再包含路由的空节点会被删除
+func (n *node) update(tokens []patternToken, fn func(leaf *node)) bool {
+	if len(tokens) == 0 {
+		if n.route == nil {
+			return false
+		}
+
+		fn(n)
+		n.updatePriority()
+		return true
+	}
+
+	token, rest := tokens[0], tokens[1:]
+	switch token.nType {
+	case static:
+		if token.text == "" {
+			return n.update(rest, fn)
+		}
+
+		i := n.staticChild(token.text[0])
+		if i < 0 || !strings.HasPrefix(token.text, n.children[i].path) {
+			return false
+		}
+
+		child := n.children[i].clone()
+		if suffix := token.text[len(child.path):]; suffix != "" {
+			rest = append([]patternToken{{nType: static, text: suffix}}, rest...)
+		}
+
+		if !child.update(rest, fn) {
+			return false
+		}
+
+		if child.empty() {
+			n.indices = n.indices[:i] + n.indices[i+1:]
+			n.children = append(n.children[:i], n.children[i+1:]...)
+		} else {
+			n.children[i] = child
+		}
+	case param:
+		i := 0
+		for ; i < len(n.params); i++ {
+			if n.params[i].key == token.text && n.params[i].cons.String() == token.constraint {
+				break
+			}
+		}
+
+		if i == len(n.params) {
+			return false
+		}
+
+		child := n.params[i].clone()
+		if !child.update(rest, fn) {
+			return false
+		}
+
+		if child.empty() {
+			n.params = append(n.params[:i], n.params[i+1:]...)
+		} else {
+			n.params[i] = child
+		}
+	case catchAll:
+		if n.wildcard == nil || n.wildcard.key != token.text {
+			return false
+		}
+
+		child := n.wildcard.clone()
+		if !child.update(rest, fn) {
+			return false
+		}
+
+		n.wildcard = child
+		if child.empty() {
+			n.wildcard = nil
+		}
+	}
+
+	n.updatePriority()
+	return true
 }
 
-func (n *node) insert(pattern string, parts []string, height int) {
-	if len(parts) == height {
-		n.pattern = pattern
-		return
+// updatePriority recomputes the number of routes registered below n.
+func (n *node) updatePriority() {
+	n.priority = 0
+	if n.route != nil {
+		n.priority = 1
+	}
+
+	for _, child := range n.children {
+		n.priority += child.priority
 	}
 
-	part := parts[height]
-	child := n.matchChild(part)
-	if child == nil {
-		child = &node{part: part, isWild: part[0] == ':' || part[0] == '*'}
-		n.children = append(n.children, child)
+	for _, child := range n.params {
+		n.priority += child.priority
 	}
-	child.insert(pattern, parts, height+1)
+
+	if n.wildcard != nil {
+		n.priority += n.wildcard.priority
+	}
+}
+
+// empty reports whether n has neither a route nor children.
+func (n *node) empty() bool {
+	return n.route == nil && len(n.children) == 0 && len(n.params) == 0 && n.wildcard == nil
+}
+
+// firstPattern returns the pattern of the first route registered below n.
+func (n *node) firstPattern() string {
+	var nodes []*node
+	n.travel(&nodes)
+	if len(nodes) == 0 {
+		return ""
+	}
+
+	return nodes[0].pattern
+}
+
+// split 将静态节点在 i 处一分为二，后半部分成为新的子节点
+func (n *node) split(i int) {
+	child := *n
+	child.path = n.path[i:]
+	child.priority--
+
+	*n = node{
+		path:     n.path[:i],
+		nType:    static,
+		indices:  child.path[:1],
+		children: []*node{&child},
+		priority: n.priority,
+	}
+}
+
+// staticChild returns the index of the static child starting with c, or -1.
+func (n *node) staticChild(c byte) int {
+	for i := 0; i < len(n.indices); i++ {
+		if n.indices[i] == c {
+			return i
+		}
+	}
+
+	return -1
 }
 
-func (n *node) search(parts []string, height int) *node {
-	if len(parts) == height || strings.HasPrefix(n.part, "*") {
-		if n.pattern == "" {
+// incrementChildPrio increments priority of the given child and reorders if necessary
+func (n *node) incrementChildPrio(pos int) int {
+	cs := n.children
+	cs[pos].priority++
+	prio := cs[pos].priority
+
+	newPos := pos
+	for ; newPos > 0 && cs[newPos-1].priority < prio; newPos-- {
+		cs[newPos-1], cs[newPos] = cs[newPos], cs[newPos-1]
+	}
+
+	if newPos != pos {
+		n.indices = n.indices[:newPos] + n.indices[pos:pos+1] + n.indices[newPos:pos] + n.indices[pos+1:]
+	}
+
+	return newPos
+}
+
+// search 查找与 path 匹配的叶子节点，路由参数按顺序追加到 ps 中
+// 当 ps 的容量足够时，查找过程不会分配内存
+func (n *node) search(path string, ps *Params) *node {
+	return n.next(path, ps)
+}
+
+// match matches path against n itself and then its children.
+func (n *node) match(path string, ps *Params) *node {
+	switch n.nType {
+	case static:
+		if len(path) < len(n.path) || path[:len(n.path)] != n.path {
 			return nil
 		}
+
+		return n.next(path[len(n.path):], ps)
+	case param:
+		segEnd := segmentEnd(path)
+		for end := n.prevEnd(path, segEnd, false); end > 0; end = n.prevEnd(path, end, false) {
+			if leaf := n.matchValue(path, end, ps); leaf != nil {
+				return leaf
+			}
+		}
+
+		return n.matchValue(path, segEnd, ps)
+	}
+
+	return nil
+}
+
+// matchValue matches path[:end] as the value of param n and the rest of path
+// against the children of n.
+func (n *node) matchValue(path string, end int, ps *Params) *node {
+	if end == 0 || (n.cons != nil && !n.cons.match(path[:end])) {
+		return nil
+	}
+
+	*ps = append(*ps, Param{Key: n.key, Value: path[:end]})
+	if leaf := n.next(path[end:], ps); leaf != nil {
+		return leaf
+	}
+
+	*ps = (*ps)[:len(*ps)-1]
+	return nil
+}
+
+// prevEnd returns the largest index before end inside the current segment
+// where the value of param n could end because one of its static children
+// starts there, or 0 if there is none. Such values are tried before the
+// whole segment, from the longest to the shortest, so that /avatar/:user.png
+// takes precedence over /avatar/:user.
+func (n *node) prevEnd(path string, end int, fold bool) int {
+	if n.indices == "" || n.indices == "/" {
+		return 0
+	}
+
+	for end--; end > 0; end-- {
+		c := path[end]
+		for i := 0; i < len(n.indices); i++ {
+			if n.indices[i] == c || (fold && lowerASCII(n.indices[i]) == lowerASCII(c)) {
+				return end
+			}
+		}
+	}
+
+	return 0
+}
+
+// next matches the rest of path against the children of n.
+func (n *node) next(path string, ps *Params) *node {
+	if path == "" && n.route != nil {
 		return n
 	}
 
-	part := parts[height]
-	children := n.matchChildren(part)
+	if path != "" {
+		if i := n.staticChild(path[0]); i >= 0 {
+			if leaf := n.children[i].match(path, ps); leaf != nil {
+				return leaf
+			}
+		}
+
+		for _, child := range n.params {
+			if leaf := child.match(path, ps); leaf != nil {
+				return leaf
+			}
+		}
+	}
 
-	for _, child := range children {
-		result := child.search(parts, height+1)
-		if result != nil {
-			return result
+	if child := n.wildcard; child != nil && child.route != nil {
+		if child.key != "" {
+			*ps = append(*ps, Param{Key: child.key, Value: path})
 		}
+
+		return child
+	}
+
+	return nil
+}
+
+// findCaseInsensitive looks up path ignoring the case of static text.
+// It returns the registered spelling of path appended to buf, or nil if no route matches.
+func (n *node) findCaseInsensitive(path string, buf []byte) []byte {
+	return n.nextCaseInsensitive(path, buf)
+}
+
+func (n *node) matchCaseInsensitive(path string, buf []byte) []byte {
+	switch n.nType {
+	case static:
+		if len(path) < len(n.path) || !strings.EqualFold(path[:len(n.path)], n.path) {
+			return nil
+		}
+
+		return n.nextCaseInsensitive(path[len(n.path):], append(buf, n.path...))
+	case param:
+		segEnd := segmentEnd(path)
+		for end := n.prevEnd(path, segEnd, true); end > 0; end = n.prevEnd(path, end, true) {
+			if out := n.matchValueCaseInsensitive(path, end, buf); out != nil {
+				return out
+			}
+		}
+
+		return n.matchValueCaseInsensitive(path, segEnd, buf)
+	}
+
+	return nil
+}
+
+func (n *node) matchValueCaseInsensitive(path string, end int, buf []byte) []byte {
+	if end == 0 || (n.cons != nil && !n.cons.match(path[:end])) {
+		return nil
+	}
+
+	return n.nextCaseInsensitive(path[end:], append(buf, path[:end]...))
+}
+
+func (n *node) nextCaseInsensitive(path string, buf []byte) []byte {
+	if path == "" && n.route != nil {
+		return buf
+	}
+
+	if path != "" {
+		for _, child := range n.children {
+			if out := child.matchCaseInsensitive(path, buf); out != nil {
+				return out
+			}
+		}
+
+		for _, child := range n.params {
+			if out := child.matchCaseInsensitive(path, buf); out != nil {
+				return out
+			}
+		}
+	}
+
+	if child := n.wildcard; child != nil && child.route != nil {
+		return append(buf, path...)
 	}
 
 	return nil
@@ -56,33 +658,62 @@ func (n *node) search(parts []string, height int) *node {
 
 // travel 用于获得所有的路由规则
 func (n *node) travel(list *([]*node)) {
-	if n.pattern != "" {
+	if n.route != nil {
 		*list = append(*list, n)
 	}
 
 	for _, child := range n.children {
 		child.travel(list)
 	}
+
+	for _, child := range n.params {
+		child.travel(list)
+	}
+
+	if n.wildcard != nil {
+		n.wildcard.travel(list)
+	}
 }
 
-// matchChild 第一个匹配成功的节点，用于插入
-func (n *node) matchChild(part string) *node {
-	for _, child := range n.children {
-		if child.part == part || child.isWild {
-			return child
-		}
+// segmentEnd returns the index of the first '/' in path, or len(path).
+func segmentEnd(path string) int {
+	if end := strings.IndexByte(path, '/'); end >= 0 {
+		return end
 	}
 
-	return nil
+	return len(path)
 }
 
-// matchChildren 所有匹配成功的节点，用于查找
-func (n *node) matchChildren(part string) []*node {
-	nodes := make([]*node, 0)
-	for _, child := range n.children {
-		if child.part == part || child.isWild {
-			nodes = append(nodes, child)
+func lowerASCII(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+
+	return c
+}
+
+func longestCommonPrefix(a, b string) int {
+	i := 0
+	max := len(a)
+	if len(b) < max {
+		max = len(b)
+	}
+
+	for i < max && a[i] == b[i] {
+		i++
+	}
+
+	return i
+}
+
+// countParams returns the number of params a lookup for pattern can produce.
+func countParams(tokens []patternToken) int {
+	count := 0
+	for _, token := range tokens {
+		if token.nType == param || (token.nType == catchAll && token.text != "") {
+			count++
 		}
 	}
-	return nodes
+
+	return count
 }
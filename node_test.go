@@ -0,0 +1,356 @@
+package slim
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// trieNode is the trie used by the router before the radix tree,
+// kept as a baseline for the benchmarks below.
+type trieNode struct {
+	pattern  string
+	part     string
+	children []*trieNode
+	isWild   bool
+}
+
+func (n *trieNode) insert(pattern string, parts []string, height int) {
+	if len(parts) == height {
+		n.pattern = pattern
+		return
+	}
+
+	part := parts[height]
+	child := n.matchChild(part)
+	if child == nil {
+		child = &trieNode{part: part, isWild: part[0] == ':' || part[0] == '*'}
+		n.children = append(n.children, child)
+	}
+	child.insert(pattern, parts, height+1)
+}
+
+func (n *trieNode) search(parts []string, height int) *trieNode {
+	if len(parts) == height || strings.HasPrefix(n.part, "*") {
+		if n.pattern == "" {
+			return nil
+		}
+		return n
+	}
+
+	part := parts[height]
+	for _, child := range n.matchChildren(part) {
+		if result := child.search(parts, height+1); result != nil {
+			return result
+		}
+	}
+
+	return nil
+}
+
+func (n *trieNode) matchChild(part string) *trieNode {
+	for _, child := range n.children {
+		if child.part == part || child.isWild {
+			return child
+		}
+	}
+
+	return nil
+}
+
+func (n *trieNode) matchChildren(part string) []*trieNode {
+	nodes := make([]*trieNode, 0)
+	for _, child := range n.children {
+		if child.part == part || child.isWild {
+			nodes = append(nodes, child)
+		}
+	}
+	return nodes
+}
+
+// parsePattern Only one * is allowed
+func parsePattern(pattern string) []string {
+	vs := strings.Split(pattern, "/")
+	parts := make([]string, 0)
+	for _, item := range vs {
+		if item != "" {
+			parts = append(parts, item)
+			if item[0] == '*' {
+				break
+			}
+		}
+	}
+
+	return parts
+}
+
+func trieGetRoute(root *trieNode, path string) (*trieNode, map[string]string) {
+	searchParts := parsePattern(path)
+	params := make(map[string]string)
+	n := root.search(searchParts, 0)
+	if n == nil {
+		return nil, nil
+	}
+
+	parts := parsePattern(n.pattern)
+	for index, part := range parts {
+		if part[0] == ':' {
+			params[part[1:]] = searchParts[index]
+		}
+		if part[0] == '*' && len(part) > 1 {
+			params[part[1:]] = strings.Join(searchParts[index:], "/")
+			break
+		}
+	}
+
+	return n, params
+}
+
+// benchRoutes returns several hundred routes shaped like a typical REST API.
+func benchRoutes() []string {
+	resources := []string{
+		"users", "orders", "products", "invoices", "payments", "shipments", "carts", "reviews",
+		"coupons", "categories", "brands", "suppliers", "warehouses", "customers", "addresses",
+		"tickets", "comments", "notifications", "subscriptions", "plans", "teams", "projects",
+		"issues", "labels", "milestones", "releases", "deployments", "webhooks", "tokens", "audits",
+	}
+
+	routes := []string{"/", "/health", "/metrics", "/assets/*filepath"}
+	for _, res := range resources {
+		routes = append(routes,
+			"/api/v1/"+res,
+			"/api/v1/"+res+"/search",
+			"/api/v1/"+res+"/export",
+			"/api/v1/"+res+"/:id",
+			"/api/v1/"+res+"/:id/history",
+			"/api/v1/"+res+"/:id/comments",
+			"/api/v1/"+res+"/:id/comments/:cid",
+			"/api/v1/"+res+"/:id/attachments/*file",
+			"/api/v2/"+res,
+			"/api/v2/"+res+"/:id",
+			"/admin/"+res,
+			"/admin/"+res+"/:id/edit",
+		)
+	}
+
+	return routes
+}
+
+var benchPaths = []string{
+	"/health",
+	"/api/v1/warehouses/search",
+	"/api/v1/audits/1024",
+	"/api/v1/tickets/42/comments/7",
+	"/api/v2/webhooks/deadbeef",
+	"/admin/subscriptions/99/edit",
+	"/api/v1/projects/3/attachments/docs/spec.pdf",
+}
+
+func newTreeRoot(routes []string) *node {
+	root := &node{}
+	for _, pattern := range routes {
+		tokens, err := parsePatternTokens(pattern)
+		if err != nil {
+			panic(err)
+		}
+
+		if _, err := root.insert(pattern, tokens, &route{method: "GET", pattern: pattern}); err != nil {
+			panic(err)
+		}
+	}
+	return root
+}
+
+// searchTest is a path looked up in a tree, an empty pattern expects no match.
+type searchTest struct {
+	path    string
+	pattern string
+	params  Params
+}
+
+// assertSearch checks the route and params found in root for each test path.
+func assertSearch(t *testing.T, root *node, tests []searchTest) {
+	t.Helper()
+	for _, tt := range tests {
+		var ps Params
+		n := root.search(tt.path, &ps)
+		if tt.pattern == "" {
+			if n != nil {
+				t.Errorf("%s: expected no match, got %s", tt.path, n.pattern)
+			}
+			continue
+		}
+
+		if n == nil || n.pattern != tt.pattern {
+			t.Errorf("%s: expected pattern %s, got %v", tt.path, tt.pattern, n)
+			continue
+		}
+
+		if fmt.Sprint(ps) != fmt.Sprint(tt.params) {
+			t.Errorf("%s: expected params %v, got %v", tt.path, tt.params, ps)
+		}
+	}
+}
+
+func TestNodeSearch(t *testing.T) {
+	root := newTreeRoot([]string{
+		"/",
+		"/hello/:name",
+		"/hello/b/c",
+		"/hi/:name",
+		"/hi/:name/profile",
+		"/assets/*filepath",
+		"/users/new",
+		"/users/:id",
+		"/users/:id/posts/:post",
+		"/src/*",
+	})
+
+	tests := []searchTest{
+		{"/", "/", nil},
+		{"/hello/heige", "/hello/:name", Params{{"name", "heige"}}},
+		{"/hello/b", "/hello/:name", Params{{"name", "b"}}},
+		{"/hello/b/c", "/hello/b/c", nil},
+		{"/hello/x/c", "", nil},
+		{"/hi/slim/profile", "/hi/:name/profile", Params{{"name", "slim"}}},
+		{"/assets/css/test.css", "/assets/*filepath", Params{{"filepath", "css/test.css"}}},
+		{"/assets/", "/assets/*filepath", Params{{"filepath", ""}}},
+		{"/users/new", "/users/new", nil},
+		{"/users/newer", "/users/:id", Params{{"id", "newer"}}},
+		{"/users/12/posts/3", "/users/:id/posts/:post", Params{{"id", "12"}, {"post", "3"}}},
+		{"/users/12/posts/", "", nil},
+		{"/users/", "", nil},
+		{"/src/a/b", "/src/*", nil},
+		{"/unknown", "", nil},
+	}
+
+	assertSearch(t, root, tests)
+}
+
+func TestNodePriority(t *testing.T) {
+	root := newTreeRoot([]string{"/a", "/b/1", "/b/2", "/b/3", "/c/1", "/c/2"})
+	n := root.children[0]
+	if n.path != "/" || n.indices != "bca" {
+		t.Fatalf("children should be ordered by priority, got indices %q", n.indices)
+	}
+}
+
+func TestNodeSearchAllocs(t *testing.T) {
+	root := newTreeRoot(benchRoutes())
+	ps := make(Params, 0, 3)
+	allocs := testing.AllocsPerRun(100, func() {
+		for _, path := range benchPaths {
+			ps = ps[:0]
+			if root.search(path, &ps) == nil {
+				t.Fatalf("%s should match", path)
+			}
+		}
+	})
+
+	if allocs != 0 {
+		t.Fatalf("search should not allocate, got %v allocs", allocs)
+	}
+}
+
+func BenchmarkTrieLookup(b *testing.B) {
+	root := &trieNode{}
+	for _, pattern := range benchRoutes() {
+		root.insert(pattern, parsePattern(pattern), 0)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, path := range benchPaths {
+			trieGetRoute(root, path)
+		}
+	}
+}
+
+func BenchmarkTreeLookup(b *testing.B) {
+	root := newTreeRoot(benchRoutes())
+	ps := make(Params, 0, 3)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, path := range benchPaths {
+			ps = ps[:0]
+			root.search(path, &ps)
+		}
+	}
+}
+
+func TestNodeSearchConstraints(t *testing.T) {
+	root := newTreeRoot([]string{
+		"/orders/:id<int>",
+		"/orders/:uuid<uuid>/items",
+		"/orders/:slug<[a-z-]+>",
+		"/orders/:name",
+		"/users/:id<uint>",
+		"/tags/:tag<alpha>",
+		"/files/:name<[a-z]+\\.(?:png|jpg)>",
+	})
+
+	tests := []searchTest{
+		{"/orders/42", "/orders/:id<int>", Params{{"id", "42"}}},
+		{"/orders/-42", "/orders/:id<int>", Params{{"id", "-42"}}},
+		{"/orders/99999999999999999999", "/orders/:name", Params{{"name", "99999999999999999999"}}},
+		{"/orders/123e4567-e89b-12d3-a456-426614174000/items", "/orders/:uuid<uuid>/items",
+			Params{{"uuid", "123e4567-e89b-12d3-a456-426614174000"}}},
+		{"/orders/big-sale", "/orders/:slug<[a-z-]+>", Params{{"slug", "big-sale"}}},
+		{"/orders/Big_Sale", "/orders/:name", Params{{"name", "Big_Sale"}}},
+		{"/users/7", "/users/:id<uint>", Params{{"id", "7"}}},
+		{"/users/-7", "", nil},
+		{"/users/abc", "", nil},
+		{"/tags/go", "/tags/:tag<alpha>", Params{{"tag", "go"}}},
+		{"/tags/go2", "", nil},
+		{"/files/logo.png", "/files/:name<[a-z]+\\.(?:png|jpg)>", Params{{"name", "logo.png"}}},
+		{"/files/logo.gif", "", nil},
+	}
+
+	assertSearch(t, root, tests)
+}
+
+func TestNodeSearchMidSegmentParams(t *testing.T) {
+	root := newTreeRoot([]string{
+		"/files/:name.:ext",
+		"/files/readme.txt",
+		"/v:version/users",
+		"/v1/users",
+		"/avatar/:user.png",
+		"/avatar/:user",
+		"/img/:id<int>.jpg",
+		"/range/:from-:to",
+		`/api/v1\:batchGet`,
+		`/api/v1\::method`,
+		`/at/12\:30`,
+	})
+
+	tests := []searchTest{
+		{"/files/report.pdf", "/files/:name.:ext", Params{{"name", "report"}, {"ext", "pdf"}}},
+		{"/files/archive.tar.gz", "/files/:name.:ext", Params{{"name", "archive.tar"}, {"ext", "gz"}}},
+		{"/files/readme.txt", "/files/readme.txt", nil},
+		{"/files/readme", "", nil},
+		{"/files/.gitignore", "", nil},
+		{"/v2/users", "/v:version/users", Params{{"version", "2"}}},
+		{"/v1/users", "/v1/users", nil},
+		{"/v/users", "", nil},
+		{"/avatar/bob.png", "/avatar/:user.png", Params{{"user", "bob"}}},
+		{"/avatar/john.doe.png", "/avatar/:user.png", Params{{"user", "john.doe"}}},
+		{"/avatar/bob.jpg", "/avatar/:user", Params{{"user", "bob.jpg"}}},
+		{"/img/12.jpg", "/img/:id<int>.jpg", Params{{"id", "12"}}},
+		{"/img/ab.jpg", "", nil},
+		{"/range/1-10", "/range/:from-:to", Params{{"from", "1"}, {"to", "10"}}},
+		{"/api/v1:batchGet", `/api/v1\:batchGet`, nil},
+		{"/api/v1:get", `/api/v1\::method`, Params{{"method", "get"}}},
+		{"/api/v1batchGet", "", nil},
+		{"/at/12:30", `/at/12\:30`, nil},
+	}
+
+	assertSearch(t, root, tests)
+
+	if fixed := root.findCaseInsensitive("/AVATAR/Bob.PNG", nil); string(fixed) != "/avatar/Bob.png" {
+		t.Fatalf("case-insensitive lookup should keep the param, got %q", fixed)
+	}
+}
@@ -0,0 +1,155 @@
+package slim
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// initQueryCache parses the query string of the request once per request.
+func (c *Context) initQueryCache() {
+	if c.queryCache != nil {
+		return
+	}
+
+	if c.Request != nil {
+		c.queryCache = c.Request.URL.Query()
+	} else {
+		c.queryCache = url.Values{}
+	}
+}
+
+// Query returns the first value of the url query key, or "" if it does not exist.
+//
+//	GET /path?id=1234&name=Manu&value=
+//	c.Query("id") == "1234"
+//	c.Query("value") == ""
+//	c.Query("wtf") == ""
+func (c *Context) Query(key string) string {
+	value, _ := c.GetQuery(key)
+	return value
+}
+
+// DefaultQuery returns the first value of the url query key,
+// or defaultValue if it does not exist.
+func (c *Context) DefaultQuery(key, defaultValue string) string {
+	if value, ok := c.GetQuery(key); ok {
+		return value
+	}
+
+	return defaultValue
+}
+
+// GetQuery is like Query, it also reports whether the key exists, even with an empty value.
+func (c *Context) GetQuery(key string) (string, bool) {
+	if values, ok := c.GetQueryArray(key); ok {
+		return values[0], true
+	}
+
+	return "", false
+}
+
+// QueryArray returns all the values of the url query key, e.g. ?tag=a&tag=b.
+func (c *Context) QueryArray(key string) []string {
+	values, _ := c.GetQueryArray(key)
+	return values
+}
+
+// GetQueryArray is like QueryArray, it also reports whether the key has at least one value.
+func (c *Context) GetQueryArray(key string) ([]string, bool) {
+	c.initQueryCache()
+	values, ok := c.queryCache[key]
+	return values, ok && len(values) > 0
+}
+
+// QueryMap returns the url query keys like key[name] as a map indexed by name,
+// e.g. ?filter[name]=x&filter[age]=3 gives {"name": "x", "age": "3"} for filter.
+func (c *Context) QueryMap(key string) map[string]string {
+	dict, _ := c.GetQueryMap(key)
+	return dict
+}
+
+// GetQueryMap is like QueryMap, it also reports whether there is at least one such key.
+func (c *Context) GetQueryMap(key string) (map[string]string, bool) {
+	c.initQueryCache()
+	return mapValues(c.queryCache, key)
+}
+
+// QueryInt returns the first value of the url query key as an int,
+// or defaultValue if it does not exist or is not a valid int.
+// A parse failure is recorded in Context.Errors as an ErrorTypeBind error.
+func (c *Context) QueryInt(key string, defaultValue int) int {
+	value, ok := c.GetQuery(key)
+	if !ok {
+		return defaultValue
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		c.queryError(key, "int", err)
+		return defaultValue
+	}
+
+	return i
+}
+
+// QueryBool returns the first value of the url query key as a bool, see strconv.ParseBool,
+// or defaultValue if it does not exist or is not a valid bool.
+// A parse failure is recorded in Context.Errors as an ErrorTypeBind error.
+func (c *Context) QueryBool(key string, defaultValue bool) bool {
+	value, ok := c.GetQuery(key)
+	if !ok {
+		return defaultValue
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		c.queryError(key, "bool", err)
+		return defaultValue
+	}
+
+	return b
+}
+
+// QueryDuration returns the first value of the url query key as a duration like 1m30s,
+// see time.ParseDuration, or defaultValue if it does not exist or is not a valid duration.
+// A parse failure is recorded in Context.Errors as an ErrorTypeBind error.
+func (c *Context) QueryDuration(key string, defaultValue time.Duration) time.Duration {
+	value, ok := c.GetQuery(key)
+	if !ok {
+		return defaultValue
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		c.queryError(key, "duration", err)
+		return defaultValue
+	}
+
+	return d
+}
+
+// queryError records the failure to parse the url query key as kind.
+func (c *Context) queryError(key string, kind string, err error) {
+	c.Error(fmt.Errorf("slim: query '%s' is not a valid %s: %v", key, kind, err)).
+		SetType(ErrorTypeBind).
+		SetMeta(H{"query": key})
+}
+
+// mapValues returns the values of the keys like key[name] indexed by name.
+func mapValues(values map[string][]string, key string) (map[string]string, bool) {
+	dict := make(map[string]string)
+	exists := false
+	for k, v := range values {
+		if i := strings.IndexByte(k, '['); i >= 1 && k[:i] == key {
+			if j := strings.IndexByte(k[i+1:], ']'); j >= 1 && len(v) > 0 {
+				exists = true
+				dict[k[i+1:][:j]] = v[0]
+			}
+		}
+	}
+
+	return dict, exists
+}
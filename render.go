@@ -0,0 +1,346 @@
+package slim
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"unicode/utf16"
+)
+
+// defaultSecureJSONPrefix is the default value of Engine.SecureJSONPrefix.
+const defaultSecureJSONPrefix = "while(1);"
+
+// jsonpCallback matches the callbacks of JSONP, a JavaScript identifier or dotted path.
+var jsonpCallback = regexp.MustCompile(`^[A-Za-z_$][\w$]*(\.[A-Za-z_$][\w$]*)*$`)
+
+// Render writes a response body in a given format, see Context.Render.
+type Render interface {
+	// Render writes the Content-Type header and the body.
+	Render(w http.ResponseWriter) error
+	// WriteContentType writes the Content-Type header only.
+	WriteContentType(w http.ResponseWriter)
+}
+
+// RenderJSON renders Data as JSON.
+type RenderJSON struct {
+	Data interface{}
+}
+
+// RenderIndentedJSON renders Data as JSON indented with 4 spaces.
+type RenderIndentedJSON struct {
+	Data interface{}
+}
+
+// RenderSecureJSON renders Data as JSON, Prefix is written before a JSON array.
+type RenderSecureJSON struct {
+	Prefix string
+	Data   interface{}
+}
+
+// RenderJSONP renders Data as JSON wrapped in a call to Callback, or as JSON with the JSON
+// Content-Type if Callback is not a JavaScript identifier or dotted path like jQuery.cb,
+// so that it cannot inject script.
+type RenderJSONP struct {
+	Callback string
+	Data     interface{}
+}
+
+// RenderAsciiJSON renders Data as JSON with the non-ASCII characters escaped as \uXXXX.
+type RenderAsciiJSON struct {
+	Data interface{}
+}
+
+// RenderPureJSON renders Data as JSON without escaping the HTML characters <, > and &.
+type RenderPureJSON struct {
+	Data interface{}
+}
+
+// RenderXML renders Data as XML.
+type RenderXML struct {
+	Data interface{}
+}
+
+// RenderText renders Format as plain text, formatted with Data if any, see fmt.Sprintf.
+type RenderText struct {
+	Format string
+	Data   []interface{}
+}
+
+// RenderReader copies Reader to the response, with the Content-Length header set
+// if ContentLength is not negative and the extra Headers.
+type RenderReader struct {
+	ContentType   string
+	ContentLength int64
+	Headers       map[string]string
+	Reader        io.Reader
+}
+
+func writeContentType(w http.ResponseWriter, contentType string) {
+	header := w.Header()
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", contentType)
+	}
+}
+
+// Render implements Render.
+func (r RenderJSON) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return json.NewEncoder(w).Encode(r.Data)
+}
+
+// WriteContentType implements Render.
+func (r RenderJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, MIMEJSON)
+}
+
+// Render implements Render.
+func (r RenderIndentedJSON) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	data, err := json.MarshalIndent(r.Data, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+// WriteContentType implements Render.
+func (r RenderIndentedJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, MIMEJSON)
+}
+
+// Render implements Render.
+func (r RenderSecureJSON) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	data, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+
+	if bytes.HasPrefix(data, []byte("[")) && bytes.HasSuffix(data, []byte("]")) {
+		if _, err := io.WriteString(w, r.Prefix); err != nil {
+			return err
+		}
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+// WriteContentType implements Render.
+func (r RenderSecureJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, MIMEJSON)
+}
+
+// Render implements Render.
+func (r RenderJSONP) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	data, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+
+	if !jsonpCallback.MatchString(r.Callback) {
+		_, err = w.Write(data)
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(r.Callback)
+	buf.WriteByte('(')
+	buf.Write(data)
+	buf.WriteString(");")
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// WriteContentType implements Render.
+func (r RenderJSONP) WriteContentType(w http.ResponseWriter) {
+	if !jsonpCallback.MatchString(r.Callback) {
+		writeContentType(w, MIMEJSON)
+		return
+	}
+
+	writeContentType(w, "application/javascript")
+}
+
+// Render implements Render.
+func (r RenderAsciiJSON) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	data, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for _, c := range string(data) {
+		switch {
+		case c < 0x80:
+			buf.WriteByte(byte(c))
+		case c > 0xFFFF:
+			r1, r2 := utf16.EncodeRune(c)
+			fmt.Fprintf(&buf, "\\u%04x\\u%04x", r1, r2)
+		default:
+			fmt.Fprintf(&buf, "\\u%04x", c)
+		}
+	}
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// WriteContentType implements Render.
+func (r RenderAsciiJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, MIMEJSON)
+}
+
+// Render implements Render.
+func (r RenderPureJSON) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(r.Data)
+}
+
+// WriteContentType implements Render.
+func (r RenderPureJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, MIMEJSON)
+}
+
+// Render implements Render.
+func (r RenderXML) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return xml.NewEncoder(w).Encode(r.Data)
+}
+
+// WriteContentType implements Render.
+func (r RenderXML) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, MIMEXML)
+}
+
+// Render implements Render.
+func (r RenderText) Render(w http.ResponseWriter) (err error) {
+	r.WriteContentType(w)
+	if len(r.Data) > 0 {
+		_, err = fmt.Fprintf(w, r.Format, r.Data...)
+	} else {
+		_, err = io.WriteString(w, r.Format)
+	}
+
+	return err
+}
+
+// WriteContentType implements Render.
+func (r RenderText) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, "text/plain")
+}
+
+// Render implements Render.
+func (r RenderReader) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	_, err := io.Copy(w, r.Reader)
+	return err
+}
+
+// WriteContentType implements Render, it also writes the Content-Length and extra headers
+// since they must be written along with the status code.
+func (r RenderReader) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, r.ContentType)
+	header := w.Header()
+	if r.ContentLength >= 0 {
+		header.Set("Content-Length", strconv.FormatInt(r.ContentLength, 10))
+	}
+
+	for key, value := range r.Headers {
+		if header.Get(key) == "" {
+			header.Set(key, value)
+		}
+	}
+}
+
+// Render writes the status code and renders r, the body is omitted for the
+// status codes that do not allow one, like 204 and 304.
+// A failure is recorded in Context.Errors as an ErrorTypeRender error.
+func (c *Context) Render(code int, r Render) {
+	r.WriteContentType(c.Writer)
+	c.Status(code)
+	if !bodyAllowedForStatus(code) {
+		return
+	}
+
+	if err := r.Render(c.Writer); err != nil {
+		c.Error(err).SetType(ErrorTypeRender)
+	}
+}
+
+// XML renders obj as XML.
+func (c *Context) XML(code int, obj interface{}) {
+	c.Render(code, RenderXML{Data: obj})
+}
+
+// IndentedJSON renders obj as JSON indented for humans, JSON is more compact.
+func (c *Context) IndentedJSON(code int, obj interface{}) {
+	c.Render(code, RenderIndentedJSON{Data: obj})
+}
+
+// SecureJSON renders obj as JSON prefixed with Engine.SecureJSONPrefix if it is an array,
+// to prevent JSON hijacking.
+func (c *Context) SecureJSON(code int, obj interface{}) {
+	prefix := defaultSecureJSONPrefix
+	if c.engine != nil {
+		prefix = c.engine.SecureJSONPrefix
+	}
+
+	c.Render(code, RenderSecureJSON{Prefix: prefix, Data: obj})
+}
+
+// JSONP renders obj as JSON wrapped in a call to the function given by the callback
+// url query, or as JSON if there is none or it is not a JavaScript identifier or dotted path.
+func (c *Context) JSONP(code int, obj interface{}) {
+	callback := c.Query("callback")
+	if !jsonpCallback.MatchString(callback) {
+		c.Render(code, RenderJSON{Data: obj})
+		return
+	}
+
+	c.Render(code, RenderJSONP{Callback: callback, Data: obj})
+}
+
+// AsciiJSON renders obj as JSON with the non-ASCII characters escaped.
+func (c *Context) AsciiJSON(code int, obj interface{}) {
+	c.Render(code, RenderAsciiJSON{Data: obj})
+}
+
+// PureJSON renders obj as JSON, unlike JSON the HTML characters are not escaped.
+func (c *Context) PureJSON(code int, obj interface{}) {
+	c.Render(code, RenderPureJSON{Data: obj})
+}
+
+// DataFromReader copies reader to the body with the given content type and extra headers,
+// contentLength is the size of the body or -1 if unknown.
+func (c *Context) DataFromReader(code int, contentLength int64, contentType string, reader io.Reader, extraHeaders map[string]string) {
+	c.Render(code, RenderReader{
+		ContentType:   contentType,
+		ContentLength: contentLength,
+		Headers:       extraHeaders,
+		Reader:        reader,
+	})
+}
+
+// bodyAllowedForStatus reports whether a response with the status code may have a body.
+func bodyAllowedForStatus(status int) bool {
+	switch {
+	case status >= 100 && status <= 199:
+		return false
+	case status == http.StatusNoContent || status == http.StatusNotModified:
+		return false
+	}
+
+	return true
+}
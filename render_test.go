@@ -0,0 +1,92 @@
+package slim
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestContextRender(t *testing.T) {
+	type user struct {
+		Name string `json:"name" xml:"name"`
+	}
+
+	engine := New()
+	engine.SecureJSONPrefix = ")]}',\n"
+	engine.GET("/xml", func(c *Context) { c.XML(http.StatusOK, user{Name: "bob"}) })
+	engine.GET("/indented", func(c *Context) { c.IndentedJSON(http.StatusOK, H{"name": "bob"}) })
+	engine.GET("/secure", func(c *Context) { c.SecureJSON(http.StatusOK, []string{"a", "b"}) })
+	engine.GET("/secure-object", func(c *Context) { c.SecureJSON(http.StatusOK, H{"a": 1}) })
+	engine.GET("/jsonp", func(c *Context) { c.JSONP(http.StatusOK, H{"a": 1}) })
+	engine.GET("/ascii", func(c *Context) { c.AsciiJSON(http.StatusOK, H{"lang": "GO语言😀", "tag": "<br>"}) })
+	engine.GET("/pure", func(c *Context) { c.PureJSON(http.StatusOK, H{"html": "<b>&</b>"}) })
+	engine.GET("/json", func(c *Context) { c.JSON(http.StatusOK, H{"html": "<b>&</b>"}) })
+	engine.GET("/text", func(c *Context) { c.String(http.StatusOK, "100%%") })
+	engine.GET("/text-format", func(c *Context) { c.String(http.StatusOK, "hello %s", "bob") })
+	engine.GET("/reader", func(c *Context) {
+		c.DataFromReader(http.StatusOK, 5, "text/csv", strings.NewReader("a,b,c"),
+			map[string]string{"Content-Disposition": `attachment; filename="a.csv"`})
+	})
+	engine.GET("/no-content", func(c *Context) { c.JSON(http.StatusNoContent, H{"a": 1}) })
+
+	tests := []struct {
+		url         string
+		contentType string
+		body        string
+	}{
+		{"/xml", MIMEXML, "<user><name>bob</name></user>"},
+		{"/indented", MIMEJSON, "{\n    \"name\": \"bob\"\n}"},
+		{"/secure", MIMEJSON, ")]}',\n[\"a\",\"b\"]"},
+		{"/secure-object", MIMEJSON, `{"a":1}`},
+		{"/jsonp?callback=x", "application/javascript", `x({"a":1});`},
+		{"/jsonp?callback=jQuery.cb_1", "application/javascript", `jQuery.cb_1({"a":1});`},
+		{"/jsonp?callback=alert('x')", MIMEJSON, "{\"a\":1}\n"},
+		{"/jsonp?callback=alert(document.domain)//", MIMEJSON, "{\"a\":1}\n"},
+		{"/jsonp?callback=a..b", MIMEJSON, "{\"a\":1}\n"},
+		{"/jsonp", MIMEJSON, "{\"a\":1}\n"},
+		{"/ascii", MIMEJSON, `{"lang":"GO\u8bed\u8a00\ud83d\ude00","tag":"\u003cbr\u003e"}`},
+		{"/pure", MIMEJSON, "{\"html\":\"<b>&</b>\"}\n"},
+		{"/json", MIMEJSON, "{\"html\":\"\\u003cb\\u003e\\u0026\\u003c/b\\u003e\"}\n"},
+		{"/text", "text/plain", "100%"},
+		{"/text-format", "text/plain", "hello bob"},
+		{"/reader", "text/csv", "a,b,c"},
+		{"/no-content", MIMEJSON, ""},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+		if w.Header().Get("Content-Type") != tt.contentType || w.Body.String() != tt.body {
+			t.Fatalf("%s: expected %s %q, got %s %q", tt.url, tt.contentType, tt.body,
+				w.Header().Get("Content-Type"), w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/reader", nil))
+	if w.Header().Get("Content-Length") != "5" || w.Header().Get("Content-Disposition") != `attachment; filename="a.csv"` {
+		t.Fatalf("the reader headers should be written, got %v", w.Header())
+	}
+}
+
+func TestContextRenderError(t *testing.T) {
+	w := httptest.NewRecorder()
+	c := &Context{Writer: w}
+	c.JSON(http.StatusOK, H{"ch": make(chan int)})
+	if len(c.Errors) != 1 || !c.Errors[0].IsType(ErrorTypeRender) || w.Body.Len() != 0 {
+		t.Fatalf("the render failure should be recorded, got %v %q", c.Errors, w.Body.String())
+	}
+
+	c.Render(http.StatusOK, RenderText{Format: "ok 100%"})
+	if len(c.Errors) != 1 || w.Body.String() != "ok 100%" {
+		t.Fatalf("unexpected errors %v %q", c.Errors, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	err := (RenderJSONP{Callback: "x);alert(1", Data: 1}).Render(w)
+	if err != nil || w.Body.String() != "1" || w.Header().Get("Content-Type") != MIMEJSON {
+		t.Fatalf("an invalid callback should render plain JSON, got %s %q %v",
+			w.Header().Get("Content-Type"), w.Body.String(), err)
+	}
+}
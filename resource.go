@@ -0,0 +1,131 @@
+package slim
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// resourceParam is the name of the param identifying a resource.
+const resourceParam = "id"
+
+// Lister lists the resources, it serves GET /path.
+type Lister interface {
+	List(c *Context)
+}
+
+// Getter returns a resource, it serves GET /path/:id.
+type Getter interface {
+	Get(c *Context)
+}
+
+// Creator creates a resource, it serves POST /path.
+type Creator interface {
+	Create(c *Context)
+}
+
+// Updater updates a resource, it serves PUT /path/:id and PATCH /path/:id.
+type Updater interface {
+	Update(c *Context)
+}
+
+// Deleter deletes a resource, it serves DELETE /path/:id.
+type Deleter interface {
+	Delete(c *Context)
+}
+
+// Resource registers the routes of a RESTful resource for the methods implemented by controller:
+//
+//	GET    /path      Lister.List     name: <name>.list
+//	POST   /path      Creator.Create  name: <name>.create
+//	GET    /path/:id  Getter.Get      name: <name>.get
+//	PUT    /path/:id  Updater.Update  name: <name>.update
+//	PATCH  /path/:id  Updater.Update  name: <name>.update
+//	DELETE /path/:id  Deleter.Delete  name: <name>.delete
+//
+// The name joins the static segments of the absolute path, e.g. v1.users.posts for
+// /users/:uid/posts in the /v1 group, so that /posts and /users/:uid/posts, or /v1/users
+// and /v2/users, can share a controller. It is "root" for a resource at the root path.
+// The resource is the last static segment of the path, e.g. posts for /users/:uid/posts.
+// Every route is tagged with the resource and has the "resource" and "action" metadata.
+// The handlers run before the controller method, after the middleware of the group.
+// It panics if controller implements none of the interfaces above.
+func (group *RouterGroup) Resource(relativePath string, controller interface{}, handlers ...HandlerFunc) *Route {
+	relativePath = strings.TrimSuffix(relativePath, "/")
+	itemPath := relativePath + "/:" + resourceParam
+	absolutePath := group.calculateAbsolutePath(relativePath)
+	if absolutePath == "" {
+		relativePath = "/" // Resource("/") on the engine
+	}
+
+	resource := resourceName(absolutePath)
+	name := resourceRouteName(absolutePath, resource)
+
+	r := &Route{engine: group.engine, router: group.router()}
+	register := func(method, path, action string, handler HandlerFunc) {
+		chain := make(HandlersChain, 0, len(handlers)+1)
+		chain = append(append(chain, handlers...), handler)
+		route := group.handle(method, path, chain).
+			Name(name+"."+action).
+			Tags(resource).
+			Meta("resource", resource).
+			Meta("action", action)
+		r.routes = append(r.routes, route.routes...)
+	}
+
+	if c, ok := controller.(Lister); ok {
+		register(http.MethodGet, relativePath, "list", c.List)
+	}
+
+	if c, ok := controller.(Creator); ok {
+		register(http.MethodPost, relativePath, "create", c.Create)
+	}
+
+	if c, ok := controller.(Getter); ok {
+		register(http.MethodGet, itemPath, "get", c.Get)
+	}
+
+	if c, ok := controller.(Updater); ok {
+		register(http.MethodPut, itemPath, "update", c.Update)
+		register(http.MethodPatch, itemPath, "update", c.Update)
+	}
+
+	if c, ok := controller.(Deleter); ok {
+		register(http.MethodDelete, itemPath, "delete", c.Delete)
+	}
+
+	if len(r.routes) == 0 {
+		panic(fmt.Sprintf("slim: resource controller %T implements none of Lister, Getter, Creator, Updater, Deleter", controller))
+	}
+
+	return r
+}
+
+// resourceName returns the last static segment of path.
+func resourceName(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		if segments[i] != "" && segments[i][0] != ':' && segments[i][0] != '*' {
+			return segments[i]
+		}
+	}
+
+	return "root"
+}
+
+// resourceRouteName joins the static segments of path with dots,
+// or returns resource if there is none.
+func resourceRouteName(path string, resource string) string {
+	var names []string
+	for _, segment := range strings.Split(path, "/") {
+		if segment != "" && segment[0] != ':' && segment[0] != '*' {
+			names = append(names, segment)
+		}
+	}
+
+	if len(names) == 0 {
+		return resource
+	}
+
+	return strings.Join(names, ".")
+}
@@ -0,0 +1,161 @@
+package slim
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// rewriteMethod is the method the rewrite rules are registered with, they apply to all methods.
+const rewriteMethod = "*"
+
+// RewriteRule rewrites the path of the requests matching a pattern before routing,
+// it is returned by Engine.Rewrite.
+type RewriteRule struct {
+	from   string
+	to     string
+	tokens []patternToken // tokens of to
+	code   int            // redirect status code, 0 for an internal rewrite
+}
+
+// rewriter holds the rewrite rules of an engine.
+type rewriter struct {
+	router *router                 // matches the from patterns
+	rules  map[string]*RewriteRule // key: from pattern
+}
+
+// Rewrite adds a rule rewriting the path of the requests matching the from pattern
+// into the to pattern before routing, for all methods and hosts:
+//
+//	engine.Rewrite("/v1/user/:id", "/api/v1/users/:id")
+//	engine.Rewrite("/docs/*page", "/help/*page").Redirect(http.StatusMovedPermanently)
+//
+// The from pattern accepts the same wildcards as routes, the :param and *catchAll
+// of the to pattern take the value of the wildcard of the same name. Query strings are kept.
+// The request is routed with the new path, the handlers see it in Context.Path and
+// Request.URL.Path. Rules are applied once, in the order of route precedence.
+// It panics if a pattern is invalid, conflicts with another rule or if the
+// to pattern uses a wildcard missing from the from pattern.
+func (engine *Engine) Rewrite(from, to string) *RewriteRule {
+	if to == "" || to[0] != '/' {
+		panic(fmt.Sprintf("slim: invalid rewrite target '%s': path must begin with '/'", to))
+	}
+
+	tokens, err := parsePatternTokens(to)
+	if err != nil {
+		panic(fmt.Sprintf("slim: invalid rewrite target '%s': %s", to, err))
+	}
+
+	fromTokens, err := parsePatternTokens(from)
+	if err != nil {
+		panic(fmt.Sprintf("slim: invalid rewrite pattern '%s': %s", from, err))
+	}
+
+	for _, token := range tokens {
+		if token.nType != static && !hasWildcard(fromTokens, token.text) {
+			panic(fmt.Sprintf("slim: rewrite target '%s' uses '%s' missing from '%s'", to, token.text, from))
+		}
+	}
+
+	if engine.rewriter == nil {
+		engine.rewriter = &rewriter{router: newRouter(), rules: make(map[string]*RewriteRule)}
+	}
+
+	if _, err := engine.rewriter.router.addRoute(rewriteMethod, from, nil); err != nil {
+		panic(err)
+	}
+
+	rule := &RewriteRule{from: from, to: to, tokens: tokens}
+	engine.rewriter.rules[from] = rule
+	debugPrintf("rewrite %-40s --> %s", from, to)
+	return rule
+}
+
+// Redirect makes the rule redirect the client to the new path with code,
+// e.g. http.StatusMovedPermanently, instead of rewriting the request internally.
+// It panics if code is not one of 301, 302, 303, 307 and 308.
+func (rule *RewriteRule) Redirect(code int) *RewriteRule {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		panic(fmt.Sprintf("slim: invalid redirect code %d for rewrite '%s'", code, rule.from))
+	}
+
+	rule.code = code
+	return rule
+}
+
+// hasWildcard reports whether tokens contain a param or catch-all named name.
+func hasWildcard(tokens []patternToken, name string) bool {
+	for _, token := range tokens {
+		if token.nType != static && token.text == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// rewrite applies the rule matching the request of c, if any.
+// The path is matched like routes, taking Engine.UseRawPath into account.
+// It reports whether the request has been redirected and must not be routed.
+func (rw *rewriter) rewrite(c *Context) bool {
+	start := len(c.Params)
+	path, unescape := routingPath(c)
+	n := rw.router.getRoute(rewriteMethod, path, &c.Params)
+	if n == nil {
+		return false
+	}
+
+	rule := rw.rules[n.pattern]
+	params := c.Params[start:]
+	c.Params = c.Params[:start]
+	if unescape {
+		unescapeParams(params)
+	}
+
+	if rule.code != 0 {
+		location := rule.fill(params, true)
+		if c.Request.URL.RawQuery != "" {
+			location += "?" + c.Request.URL.RawQuery
+		}
+
+		debugPrintf("rewrite redirect %d: %s --> %s", rule.code, c.Path, location)
+		c.StatusCode = rule.code
+		http.Redirect(c.Writer, c.Request, location, rule.code)
+		return true
+	}
+
+	req := new(http.Request)
+	*req = *c.Request
+	req.URL = new(url.URL)
+	*req.URL = *c.Request.URL
+	req.URL.Path = rule.fill(params, false)
+	req.URL.RawPath = rule.fill(params, true)
+	c.Request = req
+	c.Path = req.URL.Path
+	return false
+}
+
+// fill builds the target path of the rule with params,
+// escaped for a redirect location or url.RawPath when escape is true.
+func (rule *RewriteRule) fill(params Params, escape bool) string {
+	var b strings.Builder
+	for _, token := range rule.tokens {
+		value := token.text
+		if token.nType != static {
+			value = params.ByName(token.text)
+			if escape && token.nType == param {
+				value = url.PathEscape(value)
+			} else if escape {
+				value = escapeSegments(value)
+			}
+		}
+
+		b.WriteString(value)
+	}
+
+	return localPath(b.String())
+}
@@ -0,0 +1,241 @@
+package slim
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Route is returned by the route registration methods,
+// it allows to name the registered route and to attach metadata afterwards:
+//
+//	r.GET("/users/:id", getUser).Name("user").Tags("users").Meta("permission", "users:read")
+//	r.URL("user", 42) // == "/users/42"
+type Route struct {
+	engine *Engine
+	router *router
+	routes []*route // Any registers one route per method
+}
+
+// RouteMeta holds the metadata attached to a route with Route.Meta and Route.Tags,
+// e.g. a summary, the permission required by an auth middleware or a deprecated flag.
+// Middleware reads the metadata of the matched route with Context.Route.
+// It is shared by all the requests of the route and must not be modified.
+type RouteMeta struct {
+	Method string // http method of the route
+	Path   string // pattern of the route, e.g. /users/:id
+	Tags   []string
+	Values map[string]interface{}
+}
+
+// Get returns the value of key, m may be nil.
+func (m *RouteMeta) Get(key string) (value interface{}, exists bool) {
+	if m == nil {
+		return nil, false
+	}
+
+	value, exists = m.Values[key]
+	return
+}
+
+// GetString returns the value of key if it is a string, or "".
+func (m *RouteMeta) GetString(key string) string {
+	value, _ := m.Get(key)
+	s, _ := value.(string)
+	return s
+}
+
+// GetBool returns the value of key if it is a bool, or false.
+func (m *RouteMeta) GetBool(key string) bool {
+	value, _ := m.Get(key)
+	b, _ := value.(bool)
+	return b
+}
+
+// HasTag reports whether the route is tagged with tag, m may be nil.
+func (m *RouteMeta) HasTag(tag string) bool {
+	if m == nil {
+		return false
+	}
+
+	return containsString(m.Tags, tag)
+}
+
+// clone returns a copy of m that can be modified.
+func (m *RouteMeta) clone() *RouteMeta {
+	c := *m
+	c.Tags = append([]string(nil), m.Tags...)
+	if m.Values != nil {
+		c.Values = make(map[string]interface{}, len(m.Values)+1)
+		for key, value := range m.Values {
+			c.Values[key] = value
+		}
+	}
+
+	return &c
+}
+
+// Meta attaches the metadata key with value to the route, replacing any previous value.
+// It may be called while the engine is serving requests.
+func (r *Route) Meta(key string, value interface{}) *Route {
+	return r.update(func(rt *route) {
+		if rt.meta.Values == nil {
+			rt.meta.Values = make(map[string]interface{}, 1)
+		}
+
+		rt.meta.Values[key] = value
+	})
+}
+
+// Tags adds tags to the route.
+// It may be called while the engine is serving requests.
+func (r *Route) Tags(tags ...string) *Route {
+	return r.update(func(rt *route) {
+		for _, tag := range tags {
+			if !containsString(rt.meta.Tags, tag) {
+				rt.meta.Tags = append(rt.meta.Tags, tag)
+			}
+		}
+	})
+}
+
+// update replaces the registered routes by copies modified by fn, the metadata can be modified too.
+func (r *Route) update(fn func(rt *route)) *Route {
+	for i, rt := range r.routes {
+		updated := r.router.updateRoute(rt.method, rt.pattern, func(old *route) *route {
+			c := *old
+			c.meta = old.meta.clone()
+			fn(&c)
+			return &c
+		})
+
+		if updated != nil {
+			r.routes[i] = updated
+		}
+	}
+
+	return r
+}
+
+// Name names the route so that its url can be built with Engine.URL.
+// It panics if name is already used by a route with another pattern, of any host.
+func (r *Route) Name(name string) *Route {
+	if name == "" || len(r.routes) == 0 {
+		return r
+	}
+
+	if named, _, pattern := r.engine.lookupName(name); named != nil && named != r.router {
+		panic(fmt.Sprintf("slim: route name '%s' of '%s' is already used by route '%s'", name, r.routes[0].pattern, pattern))
+	}
+
+	r.router.setName(name, r.routes[0].pattern)
+	return r.update(func(rt *route) {
+		rt.name = name
+	})
+}
+
+// URL builds the url of the route registered with name.
+// The params fill the :param and *catchAll segments of the route in order,
+// the values are formatted with fmt.Sprint and escaped, they must satisfy the param constraints.
+// The url of a route bound to a host pattern by Engine.Host is protocol-relative,
+// like //acme.example.com/users/1, the params fill the host params first.
+func (engine *Engine) URL(name string, params ...interface{}) (string, error) {
+	r, h, pattern := engine.lookupName(name)
+	if r == nil {
+		return "", fmt.Errorf("slim: no route named '%s'", name)
+	}
+
+	if h == nil {
+		return buildURL(pattern, params...)
+	}
+
+	host, n, err := h.buildHost(params)
+	if err != nil {
+		return "", err
+	}
+
+	path, err := buildURL(pattern, params[n:]...)
+	if err != nil {
+		return "", err
+	}
+
+	return "//" + host + path, nil
+}
+
+// lookupName returns the router of the route named name, with its host router
+// if the route is bound to a host pattern, and its pattern. The router is nil if there is none.
+func (engine *Engine) lookupName(name string) (*router, *hostRouter, string) {
+	if pattern, ok := engine.router.load().names[name]; ok {
+		return engine.router, nil, pattern
+	}
+
+	for _, h := range engine.hosts {
+		if pattern, ok := h.router.load().names[name]; ok {
+			return h.router, h, pattern
+		}
+	}
+
+	return nil, nil, ""
+}
+
+// buildURL fills the wildcards of pattern with params.
+func buildURL(pattern string, params ...interface{}) (string, error) {
+	tokens, err := parsePatternTokens(pattern)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	i := 0
+	for _, token := range tokens {
+		if token.nType == static {
+			b.WriteString(token.text)
+			continue
+		}
+
+		if i >= len(params) {
+			return "", fmt.Errorf("slim: route '%s' expects more than %d params", pattern, len(params))
+		}
+
+		value := fmt.Sprint(params[i])
+		i++
+		if token.nType == catchAll {
+			b.WriteString(escapeSegments(value))
+			continue
+		}
+
+		if value == "" {
+			return "", fmt.Errorf("slim: param ':%s' of route '%s' is empty", token.text, pattern)
+		}
+
+		if token.constraint != "" {
+			cons, err := newConstraint(token.constraint)
+			if err != nil {
+				return "", err
+			}
+
+			if !cons.match(value) {
+				return "", fmt.Errorf("slim: param ':%s' of route '%s' does not match <%s>: %s",
+					token.text, pattern, token.constraint, value)
+			}
+		}
+
+		b.WriteString(url.PathEscape(value))
+	}
+
+	if i != len(params) {
+		return "", fmt.Errorf("slim: route '%s' expects %d params, got %d", pattern, i, len(params))
+	}
+
+	return b.String(), nil
+}
+
+// escapeSegments escapes every segment of a catch-all value and keeps the slashes.
+func escapeSegments(value string) string {
+	segments := strings.Split(value, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return strings.Join(segments, "/")
+}
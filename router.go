@@ -1,80 +1,276 @@
 package slim
 
 import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
+// RouteConflictError describes a route that cannot be registered because it
+// conflicts with a route already registered for the same http method.
+type RouteConflictError struct {
+	Method   string // http method of both routes
+	Pattern  string // pattern being registered
+	Existing string // pattern already registered
+	Reason   string // what exactly conflicts
+}
+
+// Error implements the error interface.
+func (e *RouteConflictError) Error() string {
+	if e.Pattern == e.Existing {
+		return fmt.Sprintf("slim: %s route '%s' is already registered", e.Method, e.Pattern)
+	}
+
+	return fmt.Sprintf("slim: %s route '%s' conflicts with existing route '%s': %s",
+		e.Method, e.Pattern, e.Existing, e.Reason)
+}
+
+// route stores everything the router knows about a registered route.
+// A route is never modified once it is registered.
+type route struct {
+	method   string
+	pattern  string
+	handlers HandlersChain // the last one is the main handler
+	name     string        // set by Route.Name
+	meta     *RouteMeta    // never nil, replaced as a whole by Route.Meta and Route.Tags
+}
+
+// router 的路由表可以在处理请求的同时修改:
+// 查找方通过 atomic.Value 读取不可变的 routerState，无需加锁；
+// 修改方在 mu 的保护下复制需要修改的部分(copy-on-write)，然后整体替换 routerState
 type router struct {
-	roots    map[string]*node
-	handlers map[string]HandlerFunc
+	mu    sync.Mutex   // serializes the writers
+	state atomic.Value // *routerState
+}
+
+// routerState is an immutable snapshot of the routing table.
+type routerState struct {
+	roots     map[string]*node
+	routes    map[string]*route // key: method-pattern
+	names     map[string]string // route name -> pattern
+	maxParams int               // 单个路由最多的参数个数，用于预分配 Params
 }
 
 func newRouter() *router {
-	return &router{
-		roots:    make(map[string]*node),
-		handlers: make(map[string]HandlerFunc),
+	r := &router{}
+	r.state.Store(&routerState{
+		roots:  make(map[string]*node),
+		routes: make(map[string]*route),
+		names:  make(map[string]string),
+	})
+
+	return r
+}
+
+// load returns the current routing table, it must not be modified.
+func (r *router) load() *routerState {
+	return r.state.Load().(*routerState)
+}
+
+// copy returns a copy of s whose maps can be modified,
+// the trees are shared and must be copied with node.clone before being modified.
+func (s *routerState) copy() *routerState {
+	c := &routerState{
+		roots:     make(map[string]*node, len(s.roots)),
+		routes:    make(map[string]*route, len(s.routes)+1),
+		names:     make(map[string]string, len(s.names)),
+		maxParams: s.maxParams,
+	}
+
+	for method, root := range s.roots {
+		c.roots[method] = root
+	}
+
+	for key, rt := range s.routes {
+		c.routes[key] = rt
 	}
+
+	for name, pattern := range s.names {
+		c.names[name] = pattern
+	}
+
+	return c
 }
 
-// parsePattern Only one * is allowed
-func parsePattern(pattern string) []string {
-	vs := strings.Split(pattern, "/")
-	parts := make([]string, 0)
-	for _, item := range vs {
-		if item != "" {
-			parts = append(parts, item)
-			if item[0] == '*' {
-				break
-			}
+// addRoute registers handlers for method and pattern.
+// It returns a *RouteConflictError if pattern conflicts with an existing route.
+// It is safe to call while requests are being served.
+func (r *router) addRoute(method string, pattern string, handlers HandlersChain) (*route, error) {
+	if pattern == "" || pattern[0] != '/' {
+		return nil, fmt.Errorf("slim: invalid %s route '%s': path must begin with '/'", method, pattern)
+	}
+
+	tokens, err := parsePatternTokens(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("slim: invalid %s route '%s': %s", method, pattern, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	root := &node{}
+	if old, ok := r.load().roots[method]; ok {
+		root = old.clone()
+	}
+
+	rt := &route{
+		method:   method,
+		pattern:  pattern,
+		handlers: handlers,
+		meta:     &RouteMeta{Method: method, Path: pattern},
+	}
+	if _, err := root.insert(pattern, tokens, rt); err != nil {
+		conflict, ok := err.(*RouteConflictError)
+		if !ok {
+			return nil, fmt.Errorf("slim: invalid %s route '%s': %s", method, pattern, err)
 		}
+
+		conflict.Method = method
+		conflict.Pattern = pattern
+		return nil, conflict
+	}
+
+	s := r.load().copy()
+	s.roots[method] = root
+	s.routes[method+"-"+pattern] = rt
+	if count := countParams(tokens); count > s.maxParams {
+		s.maxParams = count
 	}
 
-	return parts
+	r.state.Store(s)
+	return rt, nil
 }
 
-func (r *router) addRoute(method string, pattern string, handler HandlerFunc) {
-	parts := parsePattern(pattern)
-	key := method + "-" + pattern
-	_, ok := r.roots[method]
+// removeRoute removes the route registered for method and pattern,
+// it reports whether such a route existed.
+// It is safe to call while requests are being served.
+func (r *router) removeRoute(method string, pattern string) bool {
+	tokens, err := parsePatternTokens(pattern)
+	if err != nil {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old, ok := r.load().roots[method]
 	if !ok {
-		r.roots[method] = &node{}
+		return false
 	}
 
-	r.roots[method].insert(pattern, parts, 0)
-	r.handlers[key] = handler
+	root := old.clone()
+	removed := root.update(tokens, func(leaf *node) {
+		leaf.route = nil
+		leaf.pattern = ""
+	})
+	if !removed {
+		return false
+	}
+
+	s := r.load().copy()
+	s.roots[method] = root
+	if root.empty() {
+		delete(s.roots, method)
+	}
+
+	delete(s.routes, method+"-"+pattern)
+	if !s.hasPattern(pattern) {
+		for name, p := range s.names {
+			if p == pattern {
+				delete(s.names, name)
+			}
+		}
+	}
+
+	r.state.Store(s)
+	return true
 }
 
-func (r *router) getRoute(method string, path string) (*node, map[string]string) {
-	searchParts := parsePattern(path)
-	params := make(map[string]string)
-	root, ok := r.roots[method]
+// updateRoute replaces the route registered for method and pattern by the result of fn,
+// it returns the new route or nil if no such route is registered.
+// fn must return a copy of the route, the given one may be in use.
+func (r *router) updateRoute(method string, pattern string, fn func(rt *route) *route) *route {
+	tokens, err := parsePatternTokens(pattern)
+	if err != nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
+	old, ok := r.load().roots[method]
 	if !ok {
-		return nil, nil
+		return nil
 	}
 
-	n := root.search(searchParts, 0)
-	if n != nil {
-		parts := parsePattern(n.pattern)
-		for index, part := range parts {
-			if part[0] == ':' {
-				params[part[1:]] = searchParts[index]
-			}
-			if part[0] == '*' && len(part) > 1 {
-				params[part[1:]] = strings.Join(searchParts[index:], "/")
-				break
-			}
+	var rt *route
+	root := old.clone()
+	updated := root.update(tokens, func(leaf *node) {
+		rt = fn(leaf.route)
+		leaf.route = rt
+	})
+	if !updated {
+		return nil
+	}
+
+	s := r.load().copy()
+	s.roots[method] = root
+	s.routes[method+"-"+pattern] = rt
+	r.state.Store(s)
+	return rt
+}
+
+// hasPattern reports whether a route is registered with pattern for any method.
+func (s *routerState) hasPattern(pattern string) bool {
+	for _, rt := range s.routes {
+		if rt.pattern == pattern {
+			return true
 		}
+	}
+
+	return false
+}
+
+// setName names pattern so that its url can be built with Engine.URL.
+// It panics if name is already used by a route with another pattern.
+func (r *router) setName(name string, pattern string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.load().names[name]; ok {
+		if existing != pattern {
+			panic(fmt.Sprintf("slim: route name '%s' of '%s' is already used by route '%s'", name, pattern, existing))
+		}
+
+		return
+	}
+
+	s := r.load().copy()
+	s.names[name] = pattern
+	r.state.Store(s)
+}
 
-		return n, params
+// getRoute returns the leaf node matching method and path,
+// the route params are appended to ps which is left unchanged if nothing matches.
+func (r *router) getRoute(method string, path string, ps *Params) *node {
+	root, ok := r.load().roots[method]
+	if !ok {
+		return nil
 	}
 
-	return nil, nil
+	return root.search(path, ps)
 }
 
 // GetRoutes 获得所有的路由规则
 func (r *router) GetRoutes(method string) []*node {
-	root, ok := r.roots[method]
+	root, ok := r.load().roots[method]
 	if !ok {
 		return nil
 	}
@@ -84,15 +280,222 @@ func (r *router) GetRoutes(method string) []*node {
 	return nodes
 }
 
+// allowed returns the methods other than reqMethod registered for path,
+// sorted and comma separated as expected by the Allow header.
+// The path "*" matches every registered method.
+// HEAD and OPTIONS are included when the engine answers them automatically.
+func (r *router) allowed(path string, reqMethod string, engine *Engine) string {
+	s := r.load()
+	ps := make(Params, 0, s.maxParams)
+	allow := make([]string, 0, len(s.roots)+2)
+	for method, root := range s.roots {
+		if method == reqMethod {
+			continue
+		}
+
+		ps = ps[:0]
+		if path == "*" || root.search(path, &ps) != nil {
+			allow = append(allow, method)
+		}
+	}
+
+	if len(allow) == 0 {
+		return ""
+	}
+
+	if engine.HandleHEAD && containsString(allow, http.MethodGet) && !containsString(allow, http.MethodHead) {
+		allow = append(allow, http.MethodHead)
+	}
+
+	if engine.HandleOPTIONS && !containsString(allow, http.MethodOptions) {
+		allow = append(allow, http.MethodOptions)
+	}
+
+	sort.Strings(allow)
+	return strings.Join(allow, ", ")
+}
+
+// redirectPath returns the registered path a request for method and path
+// should be redirected to according to the engine redirect options, or "".
+func (r *router) redirectPath(method string, path string, engine *Engine) string {
+	if !engine.RedirectTrailingSlash && !engine.RedirectFixedPath {
+		return ""
+	}
+
+	methods := []string{method}
+	if method == http.MethodHead && engine.HandleHEAD {
+		methods = append(methods, http.MethodGet)
+	}
+
+	s := r.load()
+	ps := make(Params, 0, s.maxParams)
+	for _, m := range methods {
+		root, ok := s.roots[m]
+		if !ok {
+			continue
+		}
+
+		if engine.RedirectTrailingSlash && path != "" {
+			ps = ps[:0]
+			if alt := toggleTrailingSlash(path); alt != "" && root.search(alt, &ps) != nil {
+				return alt
+			}
+		}
+
+		if engine.RedirectFixedPath {
+			clean := cleanPath(path)
+			if fixed := root.findCaseInsensitive(clean, make([]byte, 0, len(clean)+1)); fixed != nil && string(fixed) != path {
+				return string(fixed)
+			}
+
+			if alt := toggleTrailingSlash(clean); engine.RedirectTrailingSlash && alt != "" {
+				if fixed := root.findCaseInsensitive(alt, make([]byte, 0, len(alt))); fixed != nil {
+					return string(fixed)
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+// toggleTrailingSlash adds a trailing slash to path or removes it.
+// It returns "" for the root path.
+func toggleTrailingSlash(path string) string {
+	if path == "/" {
+		return ""
+	}
+
+	if lastChar(path) == '/' {
+		return path[:len(path)-1]
+	}
+
+	return path + "/"
+}
+
+// redirectRequest redirects c permanently to the escaped path location, keeping the query string.
+// GET and HEAD requests get 301, other methods 308 so that the body is sent again.
+func redirectRequest(c *Context, location string) {
+	code := http.StatusMovedPermanently
+	if c.Method != http.MethodGet && c.Method != http.MethodHead {
+		code = http.StatusPermanentRedirect
+	}
+
+	location = localPath(location)
+	if c.Request.URL.RawQuery != "" {
+		location += "?" + c.Request.URL.RawQuery
+	}
+
+	debugPrintf("redirecting request %d: %s --> %s", code, c.Path, location)
+	c.StatusCode = code
+	http.Redirect(c.Writer, c.Request, location, code)
+}
+
+// routingPath returns the path of the request used to find the route
+// and whether the params matched against it must be unescaped, see Engine.UseRawPath.
+func routingPath(c *Context) (path string, unescape bool) {
+	if c.engine.UseRawPath && c.Request.URL.RawPath != "" {
+		return c.Request.URL.RawPath, c.engine.UnescapePathValues
+	}
+
+	return c.Path, false
+}
+
 func (r *router) handle(c *Context) {
-	n, params := r.getRoute(c.Method, c.Path)
+	path, unescape := routingPath(c)
+
+	start := len(c.Params)
+	n := r.getRoute(c.Method, path, &c.Params)
+	if n == nil && c.Method == http.MethodHead && c.engine.HandleHEAD {
+		if n = r.getRoute(http.MethodGet, path, &c.Params); n != nil {
+			c.Writer = bodylessResponseWriter{c.Writer}
+		}
+	}
+
 	if n != nil {
-		key := c.Method + "-" + n.pattern
-		c.Params = params
-		c.handlers = append(c.handlers, r.handlers[key])
-	} else {
-		c.handlers = append(c.handlers, c.engine.noRoute...)
+		if unescape {
+			unescapeParams(c.Params[start:])
+		}
+
+		c.route = n.route
+		c.handlers = n.route.handlers
+		c.Next()
+		return
 	}
 
+	if c.Method == http.MethodOptions && c.engine.HandleOPTIONS {
+		if allow := r.allowed(path, c.Method, c.engine); allow != "" {
+			c.SetHeader("Allow", allow)
+			c.handlers = c.engine.optionsHandlers(r, c.Path)
+			c.Next()
+			return
+		}
+	}
+
+	if c.Method != http.MethodConnect && path != "/" {
+		if location := r.redirectPath(c.Method, path, c.engine); location != "" {
+			if path == c.Path {
+				// routed on the unescaped path, a %3F or %23 must not become a query or fragment
+				location = escapeSegments(location)
+			}
+
+			redirectRequest(c, location)
+			return
+		}
+	}
+
+	if c.engine.HandleMethodNotAllowed {
+		if allow := r.allowed(path, c.Method, c.engine); allow != "" {
+			c.SetHeader("Allow", allow)
+			c.handlers = c.engine.noMethodHandlers(r, c.Path)
+			c.Next()
+			return
+		}
+	}
+
+	c.handlers = c.engine.noRouteHandlers(r, c.Path)
 	c.Next()
 }
+
+// unescapeParams unescapes the values of params matched against an escaped path,
+// a value that cannot be unescaped is kept as is.
+func unescapeParams(params Params) {
+	for i := range params {
+		if value, err := url.PathUnescape(params[i].Value); err == nil {
+			params[i].Value = value
+		}
+	}
+}
+
+// bodylessResponseWriter discards the response body,
+// it lets GET handlers serve HEAD requests.
+type bodylessResponseWriter struct {
+	http.ResponseWriter
+}
+
+// Write implements io.Writer, it reports b as written without writing it.
+func (w bodylessResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+// Flush implements http.Flusher, it sends the headers written so far
+// if the underlying writer supports it.
+func (w bodylessResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker if the underlying writer supports it.
+func (w bodylessResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+
+	return nil, nil, errors.New("slim: the response writer does not implement http.Hijacker")
+}
+
+// Unwrap returns the underlying writer, see http.ResponseController.
+func (w bodylessResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -1,9 +1,11 @@
 package slim
 
 import (
+	"fmt"
 	"net/http"
 	"path"
 	"regexp"
+	"strings"
 )
 
 var (
@@ -21,19 +23,20 @@ type IRouter interface {
 type IRoutes interface {
 	Use(middlewares ...HandlerFunc)
 
-	Handle(httpMethod, relativePath string, handler HandlerFunc)
-	Any(string, HandlerFunc)
-	GET(string, HandlerFunc)
-	POST(string, HandlerFunc)
-	DELETE(string, HandlerFunc)
-	PATCH(string, HandlerFunc)
-	PUT(string, HandlerFunc)
-	HEAD(string, HandlerFunc)
-	CONNECT(pattern string, handler HandlerFunc)
-	OPTIONS(pattern string, handler HandlerFunc)
-	TRACE(pattern string, handler HandlerFunc)
+	Handle(httpMethod, relativePath string, handlers ...HandlerFunc) *Route
+	Any(string, ...HandlerFunc) *Route
+	GET(string, ...HandlerFunc) *Route
+	POST(string, ...HandlerFunc) *Route
+	DELETE(string, ...HandlerFunc) *Route
+	PATCH(string, ...HandlerFunc) *Route
+	PUT(string, ...HandlerFunc) *Route
+	HEAD(string, ...HandlerFunc) *Route
+	CONNECT(pattern string, handlers ...HandlerFunc) *Route
+	OPTIONS(pattern string, handlers ...HandlerFunc) *Route
+	TRACE(pattern string, handlers ...HandlerFunc) *Route
 
 	Static(relativePath string, root string)
+	Mount(prefix string, handler http.Handler) *Route
 }
 
 // 判断是否实现了IRouter接口
@@ -45,6 +48,13 @@ type RouterGroup struct {
 	handlers HandlersChain // support middleware
 	engine   *Engine       // all groups share a Engine instance
 	parent   *RouterGroup  // support nesting
+	host     *hostRouter   // set for the groups created by Engine.Host
+
+	noRoute     HandlersChain // set by RouterGroup.NoRoute
+	allNoRoute  HandlersChain // group middleware followed by noRoute
+	noMethod    HandlersChain // set by RouterGroup.NoMethod
+	allNoMethod HandlersChain // group middleware followed by noMethod
+	allOptions  HandlersChain // group middleware followed by the automatic OPTIONS reply
 }
 
 // Group creates a new router group. You should add all the routes
@@ -57,8 +67,10 @@ func (group *RouterGroup) Group(relativePath string, handlers ...HandlerFunc) *R
 		engine:   engine,
 		parent:   group,
 		handlers: handlers,
+		host:     group.host,
 	}
 
+	newGroup.rebuildOptionsHandlers()
 	engine.groups = append(engine.groups, newGroup)
 
 	debugPrintf("engine groups len: %d", len(engine.groups))
@@ -66,24 +78,94 @@ func (group *RouterGroup) Group(relativePath string, handlers ...HandlerFunc) *R
 	return newGroup
 }
 
-// Use is defined to add middleware to the group
+// Use is defined to add middleware to the group.
+// The middleware is captured by the routes registered after this call.
 func (group *RouterGroup) Use(middlewares ...HandlerFunc) {
 	group.handlers = append(group.handlers, middlewares...)
+	group.engine.rebuildGroupHandlers()
+}
+
+// NoRoute sets the handlers called when a request below the group prefix matches no route.
+// The group with the longest prefix matching the request path wins, the middleware of
+// the group and its parents runs before handlers. Engine.NoRoute applies to the other requests.
+func (group *RouterGroup) NoRoute(handlers ...HandlerFunc) {
+	group.noRoute = handlers
+	group.engine.rebuildGroupHandlers()
+}
+
+// NoMethod sets the handlers called when HandleMethodNotAllowed is enabled and a request
+// below the group prefix matches routes of other methods only, like RouterGroup.NoRoute.
+func (group *RouterGroup) NoMethod(handlers ...HandlerFunc) {
+	group.noMethod = handlers
+	group.engine.rebuildGroupHandlers()
 }
 
 // Handle registers a new request handle and middleware with the given path and method.
-func (group *RouterGroup) Handle(httpMethod, relativePath string, handler HandlerFunc) {
+// The last handler should be the real handler, the other ones should be middleware
+// that can and should be shared among different routes.
+func (group *RouterGroup) Handle(httpMethod, relativePath string, handlers ...HandlerFunc) *Route {
 	if matched := regEnLetter.MatchString(httpMethod); !matched {
 		panic("http method " + httpMethod + " is not valid")
 	}
 
-	group.handle(httpMethod, relativePath, handler)
+	return group.handle(httpMethod, relativePath, handlers)
+}
+
+func (group *RouterGroup) handle(method string, relativePath string, handlers HandlersChain) *Route {
+	if len(handlers) == 0 {
+		panic("there must be at least one handler")
+	}
+
+	r, err := group.addRoute(method, relativePath, handlers)
+	if err != nil {
+		panic(err)
+	}
+
+	return r
 }
 
-func (group *RouterGroup) handle(method string, relativePath string, handler HandlerFunc) {
+// addRoute registers the route with the middleware of the group and returns the error instead of panicking.
+func (group *RouterGroup) addRoute(method string, relativePath string, handlers HandlersChain) (*Route, error) {
+	handlers = group.combineHandlers(handlers)
 	absolutePath := group.calculateAbsolutePath(relativePath)
-	debugPrintf("Route %4s - %s", method, absolutePath)
-	group.engine.router.addRoute(method, absolutePath, handler)
+	rt, err := group.router().addRoute(method, absolutePath, handlers)
+	if err != nil {
+		return nil, err
+	}
+
+	if IsDebugging() {
+		host := ""
+		if group.host != nil {
+			host = group.host.pattern
+		}
+
+		debugPrintRoute(rt.info(host))
+	}
+
+	return &Route{engine: group.engine, router: group.router(), routes: []*route{rt}}, nil
+}
+
+// AddRoute registers a route at runtime, it may be called while the engine is serving requests.
+// Unlike Handle it returns an error instead of panicking, the middleware of the group runs
+// before handlers. On a group returned by Engine.Host the route serves that host only.
+// Requests already being handled keep using the routes they were matched against.
+func (group *RouterGroup) AddRoute(method, relativePath string, handlers ...HandlerFunc) (*Route, error) {
+	if !regEnLetter.MatchString(method) {
+		return nil, fmt.Errorf("slim: http method %s is not valid", method)
+	}
+
+	if len(handlers) == 0 {
+		return nil, fmt.Errorf("slim: %s route '%s' has no handler", method, relativePath)
+	}
+
+	return group.addRoute(method, relativePath, handlers)
+}
+
+// RemoveRoute removes the route registered for method and path relative to the group at runtime,
+// it may be called while the engine is serving requests. On a group returned by Engine.Host
+// it removes a route of that host only. It reports whether such a route was registered.
+func (group *RouterGroup) RemoveRoute(method, relativePath string) bool {
+	return group.router().removeRoute(method, group.calculateAbsolutePath(relativePath))
 }
 
 // anyMethods any method
@@ -100,67 +182,107 @@ var anyMethods = []string{
 }
 
 // Any add any method router
-func (group *RouterGroup) Any(pattern string, handler HandlerFunc) {
+func (group *RouterGroup) Any(pattern string, handlers ...HandlerFunc) *Route {
+	r := &Route{engine: group.engine, router: group.router()}
 	for _, method := range anyMethods {
-		group.handle(method, pattern, handler)
+		r.routes = append(r.routes, group.handle(method, pattern, handlers).routes...)
 	}
+
+	return r
 }
 
 // GET defines the method to add GET request
-func (group *RouterGroup) GET(pattern string, handler HandlerFunc) {
-	group.handle(http.MethodGet, pattern, handler)
+func (group *RouterGroup) GET(pattern string, handlers ...HandlerFunc) *Route {
+	return group.handle(http.MethodGet, pattern, handlers)
 }
 
 // POST defines the method to add POST request
-func (group *RouterGroup) POST(pattern string, handler HandlerFunc) {
-	group.handle("POST", pattern, handler)
+func (group *RouterGroup) POST(pattern string, handlers ...HandlerFunc) *Route {
+	return group.handle(http.MethodPost, pattern, handlers)
 }
 
 // HEAD defines the method to add HEAD request
-func (group *RouterGroup) HEAD(pattern string, handler HandlerFunc) {
-	group.handle(http.MethodHead, pattern, handler)
+func (group *RouterGroup) HEAD(pattern string, handlers ...HandlerFunc) *Route {
+	return group.handle(http.MethodHead, pattern, handlers)
 }
 
 // PUT defines the method to add PUT request
-func (group *RouterGroup) PUT(pattern string, handler HandlerFunc) {
-	group.handle(http.MethodPut, pattern, handler)
+func (group *RouterGroup) PUT(pattern string, handlers ...HandlerFunc) *Route {
+	return group.handle(http.MethodPut, pattern, handlers)
 }
 
 // PATCH defines the method to add PATCH request
-func (group *RouterGroup) PATCH(pattern string, handler HandlerFunc) {
-	group.handle(http.MethodPatch, pattern, handler)
+func (group *RouterGroup) PATCH(pattern string, handlers ...HandlerFunc) *Route {
+	return group.handle(http.MethodPatch, pattern, handlers)
 }
 
 // DELETE defines the method to add DELETE request
-func (group *RouterGroup) DELETE(pattern string, handler HandlerFunc) {
-	group.handle(http.MethodDelete, pattern, handler)
+func (group *RouterGroup) DELETE(pattern string, handlers ...HandlerFunc) *Route {
+	return group.handle(http.MethodDelete, pattern, handlers)
 }
 
 // CONNECT defines the method to add CONNECT request
-func (group *RouterGroup) CONNECT(pattern string, handler HandlerFunc) {
-	group.handle(http.MethodConnect, pattern, handler)
+func (group *RouterGroup) CONNECT(pattern string, handlers ...HandlerFunc) *Route {
+	return group.handle(http.MethodConnect, pattern, handlers)
 }
 
 // OPTIONS defines the method to add OPTIONS request
-func (group *RouterGroup) OPTIONS(pattern string, handler HandlerFunc) {
-	group.handle(http.MethodOptions, pattern, handler)
+func (group *RouterGroup) OPTIONS(pattern string, handlers ...HandlerFunc) *Route {
+	return group.handle(http.MethodOptions, pattern, handlers)
 }
 
-// TRACE defines the method to add OPTIONS request
-func (group *RouterGroup) TRACE(pattern string, handler HandlerFunc) {
-	group.handle(http.MethodTrace, pattern, handler)
+// TRACE defines the method to add TRACE request
+func (group *RouterGroup) TRACE(pattern string, handlers ...HandlerFunc) *Route {
+	return group.handle(http.MethodTrace, pattern, handlers)
 }
 
+// combineHandlers returns the middleware of the group and all its parents,
+// outermost group first, followed by handlers.
 func (group *RouterGroup) combineHandlers(handlers HandlersChain) HandlersChain {
-	finalSize := len(group.handlers) + len(handlers)
+	finalSize := len(handlers)
+	var groups []*RouterGroup
+	for g := group; g != nil; g = g.parent {
+		groups = append(groups, g)
+		finalSize += len(g.handlers)
+	}
+
 	if finalSize >= abortIndex {
 		panic("too many handlers")
 	}
 
-	mergedHandlers := make(HandlersChain, finalSize)
-	copy(mergedHandlers, group.handlers)
-	copy(mergedHandlers[len(group.handlers):], handlers)
-	return mergedHandlers
+	mergedHandlers := make(HandlersChain, 0, finalSize)
+	for i := len(groups) - 1; i >= 0; i-- {
+		mergedHandlers = append(mergedHandlers, groups[i].handlers...)
+	}
+
+	return append(mergedHandlers, handlers...)
+}
+
+// rebuildOptionsHandlers rebuilds the chain replying to the automatic OPTIONS requests
+// below the group, see Engine.HandleOPTIONS.
+func (group *RouterGroup) rebuildOptionsHandlers() {
+	group.allOptions = group.combineHandlers(HandlersChain{replyOptions})
+}
+
+// replyOptions replies to an automatic OPTIONS request, the Allow header is already set.
+func replyOptions(c *Context) {
+	c.Status(http.StatusNoContent)
+}
+
+// covers reports whether the requests for path routed by r belong to the group.
+func (group *RouterGroup) covers(r *router, path string) bool {
+	prefix := strings.TrimSuffix(group.prefix, "/")
+	return group.router() == r && strings.HasPrefix(path, prefix) &&
+		(len(path) == len(prefix) || path[len(prefix)] == '/')
+}
+
+// router returns the router the routes of the group are registered to.
+func (group *RouterGroup) router() *router {
+	if group.host != nil {
+		return group.host.router
+	}
+
+	return group.engine.router
 }
 
 func (group *RouterGroup) calculateAbsolutePath(relativePath string) string {
@@ -1,10 +1,19 @@
 package slim
 
 import (
+	"bytes"
 	"fmt"
+	"html/template"
 	"log"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func newTestRouter() *router {
@@ -32,7 +41,8 @@ func TestParsePattern(t *testing.T) {
 
 func TestGetRoute(t *testing.T) {
 	r := newTestRouter()
-	n, ps := r.getRoute("GET", "/hello/heige")
+	var ps Params
+	n := r.getRoute("GET", "/hello/heige", &ps)
 
 	if n == nil {
 		t.Fatal("nil shouldn't be returned")
@@ -42,24 +52,26 @@ func TestGetRoute(t *testing.T) {
 		t.Fatal("should match /hello/:name")
 	}
 
-	if ps["name"] != "heige" {
+	if ps.ByName("name") != "heige" {
 		t.Fatal("name should be equal to 'heige'")
 	}
 
-	fmt.Printf("matched path: %s, params['name']: %s\n", n.pattern, ps["name"])
+	fmt.Printf("matched path: %s, params['name']: %s\n", n.pattern, ps.ByName("name"))
 
 }
 
 func TestGetRoute2(t *testing.T) {
 	r := newTestRouter()
-	n1, ps1 := r.getRoute("GET", "/assets/file1.txt")
-	ok1 := n1.pattern == "/assets/*filepath" && ps1["filepath"] == "file1.txt"
+	var ps1 Params
+	n1 := r.getRoute("GET", "/assets/file1.txt", &ps1)
+	ok1 := n1.pattern == "/assets/*filepath" && ps1.ByName("filepath") == "file1.txt"
 	if !ok1 {
 		t.Fatal("pattern shoule be /assets/*filepath & filepath shoule be file1.txt")
 	}
 
-	n2, ps2 := r.getRoute("GET", "/assets/css/test.css")
-	ok2 := n2.pattern == "/assets/*filepath" && ps2["filepath"] == "css/test.css"
+	var ps2 Params
+	n2 := r.getRoute("GET", "/assets/css/test.css", &ps2)
+	ok2 := n2.pattern == "/assets/*filepath" && ps2.ByName("filepath") == "css/test.css"
 	if !ok2 {
 		t.Fatal("pattern shoule be /assets/*filepath & filepath shoule be css/test.css")
 	}
@@ -76,3 +88,476 @@ func TestGetRoutes(t *testing.T) {
 		t.Fatal("the number of routes shoule be 4")
 	}
 }
+
+func TestAddRouteConflict(t *testing.T) {
+	tests := []struct {
+		routes   []string
+		existing string
+	}{
+		{[]string{"/users/:id", "/users/:name/posts"}, "/users/:id"},
+		{[]string{"/users/:id/posts", "/users/:name"}, "/users/:id/posts"},
+		{[]string{"/files/*path", "/files/*name"}, "/files/*path"},
+		{[]string{"/hello", "/hello"}, "/hello"},
+	}
+
+	for _, tt := range tests {
+		r := newRouter()
+		if _, err := r.addRoute("GET", tt.routes[0], nil); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := r.addRoute("GET", tt.routes[1], nil)
+		conflict, ok := err.(*RouteConflictError)
+		if !ok {
+			t.Fatalf("%v: expected *RouteConflictError, got %v", tt.routes, err)
+		}
+
+		if conflict.Method != "GET" || conflict.Pattern != tt.routes[1] || conflict.Existing != tt.existing {
+			t.Fatalf("%v: unexpected conflict %+v", tt.routes, conflict)
+		}
+
+		// the same pattern for another method must not conflict
+		if _, err := r.addRoute("POST", tt.routes[1], nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAddRouteInvalid(t *testing.T) {
+	for _, pattern := range []string{"", "users", "/users/:", "/files/*path/edit"} {
+		if _, err := newRouter().addRoute("GET", pattern, nil); err == nil {
+			t.Fatalf("pattern %q should be rejected", pattern)
+		}
+	}
+}
+
+func TestGetRouteInsertionOrder(t *testing.T) {
+	r1 := newRouter()
+	r1.addRoute("GET", "/hello/:name", nil)
+	r1.addRoute("GET", "/hello/b/c", nil)
+
+	r2 := newRouter()
+	r2.addRoute("GET", "/hello/b/c", nil)
+	r2.addRoute("GET", "/hello/:name", nil)
+
+	for _, r := range []*router{r1, r2} {
+		if n := r.getRoute("GET", "/hello/b/c", &Params{}); n == nil || n.pattern != "/hello/b/c" {
+			t.Fatal("static route should win over param route")
+		}
+
+		var ps Params
+		if n := r.getRoute("GET", "/hello/b", &ps); n == nil || ps.ByName("name") != "b" {
+			t.Fatal("/hello/b should match /hello/:name")
+		}
+
+		if n := r.getRoute("GET", "/hello/x/c", &Params{}); n != nil {
+			t.Fatal("/hello/x/c should not match any route")
+		}
+	}
+}
+
+func TestHandleConflictPanics(t *testing.T) {
+	defer func() {
+		err, _ := recover().(error)
+		if err == nil || err.Error() != "slim: GET route '/users/:name/posts' conflicts with existing route "+
+			"'/users/:id': wildcard ':name' conflicts with existing wildcard ':id'" {
+			t.Fatalf("unexpected panic: %v", err)
+		}
+	}()
+
+	engine := New()
+	engine.GET("/users/:id", func(c *Context) {})
+	engine.GET("/users/:name/posts", func(c *Context) {})
+}
+
+func TestEngineURL(t *testing.T) {
+	engine := New()
+	v1 := engine.Group("/v1")
+	v1.GET("/users/:id", func(c *Context) {}).Name("user")
+	v1.GET("/users/:id/files/*filepath", func(c *Context) {}).Name("user-file")
+	engine.Any("/ping", func(c *Context) {}).Name("ping")
+	engine.GET("/o/:id<int>", func(c *Context) {}).Name("order")
+	engine.Host("api.example.com").GET("/status", func(c *Context) {}).Name("status")
+	engine.Host(":tenant.example.com").GET("/users/:id", func(c *Context) {}).Name("tenant-user")
+
+	tests := []struct {
+		name   string
+		params []interface{}
+		url    string
+	}{
+		{"user", []interface{}{42}, "/v1/users/42"},
+		{"user", []interface{}{"a b/c"}, "/v1/users/a%20b%2Fc"},
+		{"user-file", []interface{}{1, "docs/a b.txt"}, "/v1/users/1/files/docs/a%20b.txt"},
+		{"ping", nil, "/ping"},
+		{"order", []interface{}{-3}, "/o/-3"},
+		{"status", nil, "//api.example.com/status"},
+		{"tenant-user", []interface{}{"acme", 7}, "//acme.example.com/users/7"},
+	}
+
+	for _, tt := range tests {
+		u, err := engine.URL(tt.name, tt.params...)
+		if err != nil || u != tt.url {
+			t.Fatalf("%s %v: expected %s, got %s %v", tt.name, tt.params, tt.url, u, err)
+		}
+	}
+
+	for _, params := range [][]interface{}{nil, {1, 2}, {""}} {
+		if _, err := engine.URL("user", params...); err == nil {
+			t.Fatalf("params %v should be rejected", params)
+		}
+	}
+
+	if _, err := engine.URL("unknown"); err == nil {
+		t.Fatal("unknown route name should be rejected")
+	}
+
+	if _, err := engine.URL("order", "abc"); err == nil {
+		t.Fatal("a param not matching its constraint should be rejected")
+	}
+
+	for _, params := range [][]interface{}{{"acme"}, {"a.b", 7}, {"", 7}} {
+		if _, err := engine.URL("tenant-user", params...); err == nil {
+			t.Fatalf("host params %v should be rejected", params)
+		}
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("a route name used on another host should panic")
+			}
+		}()
+		engine.GET("/status", func(c *Context) {}).Name("status")
+	}()
+
+	var buf strings.Builder
+	tpl := template.Must(template.New("").Funcs(engine.templateFuncMap()).Parse(`{{ url "user" .ID }}`))
+	if err := tpl.Execute(&buf, H{"ID": 7}); err != nil || buf.String() != "/v1/users/7" {
+		t.Fatalf("url template func should build the url, got %s %v", buf.String(), err)
+	}
+}
+
+func TestRouteNameConflict(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("reusing a route name for another pattern should panic")
+		}
+	}()
+
+	engine := New()
+	engine.GET("/a", func(c *Context) {}).Name("a")
+	engine.POST("/a", func(c *Context) {}).Name("a")
+	engine.GET("/b", func(c *Context) {}).Name("a")
+}
+
+func TestAddRouteConstraints(t *testing.T) {
+	r := newRouter()
+	for _, pattern := range []string{"/a/:id<int>", "/a/:slug<[a-z]+>", "/a/:name"} {
+		if _, err := r.addRoute("GET", pattern, nil); err != nil {
+			t.Fatalf("%s should not conflict: %v", pattern, err)
+		}
+	}
+
+	if _, err := r.addRoute("GET", "/a/:key<int>/b", nil); err == nil {
+		t.Fatal("same constraint with another param name should conflict")
+	}
+
+	for _, pattern := range []string{"/a/:id<uint>", "/a/:id<alnum>/b"} {
+		_, err := r.addRoute("GET", pattern, nil)
+		if conflict, ok := err.(*RouteConflictError); !ok || conflict.Existing != "/a/:id<int>" {
+			t.Fatalf("%s overlaps with /a/:id<int> and should conflict, got %v", pattern, err)
+		}
+	}
+
+	for _, pattern := range []string{"/a/:id<alpha>", "/a/:id<uuid>"} {
+		if _, err := r.addRoute("GET", pattern, nil); err != nil {
+			t.Fatalf("%s does not overlap with /a/:id<int>: %v", pattern, err)
+		}
+	}
+
+	if _, err := r.addRoute("GET", "/a/:id<alnum>", nil); err == nil {
+		t.Fatal("/a/:id<alnum> overlaps with /a/:id<alpha> and should conflict")
+	}
+
+	for _, pattern := range []string{"/b/:id<int", "/b/:id<>", "/b/:id<[a-z>", "/b/:id<int>:x", "/b/:id<(>"} {
+		if _, err := r.addRoute("GET", pattern, nil); err == nil {
+			t.Fatalf("pattern %q should be rejected", pattern)
+		}
+	}
+}
+
+func TestContextParamInt(t *testing.T) {
+	engine := New()
+	var id int
+	var err error
+	engine.GET("/orders/:id<int>", func(c *Context) {
+		id, err = c.ParamInt("id")
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders/12", nil))
+	if w.Code != http.StatusOK || err != nil || id != 12 {
+		t.Fatalf("expected id 12, got %d %v", id, err)
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders/abc", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("non int id should not match, got %d", w.Code)
+	}
+}
+
+func TestContextQuery(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet,
+		"/search?q=go&empty=&tag=a&tag=b&filter[name]=x&filter[age]=3&filter=no&page=2&size=big&debug=true&timeout=1m30s", nil)
+	c := &Context{Request: req}
+
+	if c.Query("q") != "go" || c.Query("missing") != "" || c.DefaultQuery("missing", "d") != "d" {
+		t.Fatal("unexpected Query/DefaultQuery result")
+	}
+
+	if value, ok := c.GetQuery("empty"); !ok || value != "" || c.DefaultQuery("empty", "d") != "" {
+		t.Fatal("an empty value should exist")
+	}
+
+	if fmt.Sprint(c.QueryArray("tag")) != "[a b]" || c.QueryArray("missing") != nil {
+		t.Fatalf("unexpected QueryArray result %v", c.QueryArray("tag"))
+	}
+
+	if dict, ok := c.GetQueryMap("filter"); !ok || fmt.Sprint(dict) != "map[age:3 name:x]" {
+		t.Fatalf("unexpected QueryMap result %v", dict)
+	}
+
+	if _, ok := c.GetQueryMap("tag"); ok {
+		t.Fatal("tag should not be a query map")
+	}
+
+	if c.QueryInt("page", 1) != 2 || c.QueryInt("missing", 1) != 1 || !c.QueryBool("debug", false) ||
+		c.QueryDuration("timeout", 0) != 90*time.Second || len(c.Errors) != 0 {
+		t.Fatalf("unexpected typed query result, errors %v", c.Errors)
+	}
+
+	if c.QueryInt("size", 10) != 10 || c.QueryBool("q", true) != true || c.QueryDuration("page", time.Second) != time.Second {
+		t.Fatal("invalid values should return the default value")
+	}
+
+	if len(c.Errors) != 3 || c.Errors[0].Type != ErrorTypeBind || c.Errors[0].JSON().(H)["query"] != "size" {
+		t.Fatalf("parse failures should be recorded, got %v", c.Errors)
+	}
+}
+
+func TestContextPostForm(t *testing.T) {
+	body := "name=slim&tag=a&tag=b&empty=&user[name]=x&user[age]=3"
+	req := httptest.NewRequest(http.MethodPost, "/users?name=query", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	c := &Context{Request: req}
+
+	if c.PostForm("name") != "slim" || c.DefaultPostForm("missing", "d") != "d" || c.DefaultPostForm("empty", "d") != "" {
+		t.Fatal("unexpected PostForm/DefaultPostForm result")
+	}
+
+	if fmt.Sprint(c.PostFormArray("tag")) != "[a b]" || fmt.Sprint(c.PostFormMap("user")) != "map[age:3 name:x]" {
+		t.Fatalf("unexpected PostFormArray/PostFormMap result %v %v", c.PostFormArray("tag"), c.PostFormMap("user"))
+	}
+
+	if c.Query("name") != "query" || len(c.Errors) != 0 {
+		t.Fatalf("the query should be kept apart from the form, errors %v", c.Errors)
+	}
+}
+
+func TestContextMultipartUpload(t *testing.T) {
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	mw.WriteField("title", "report")
+	for _, name := range []string{"../../evil.txt", "C:\\Users\\bob\\photo.png"} {
+		fw, _ := mw.CreateFormFile("files", name)
+		fw.Write([]byte("content of " + name))
+	}
+	mw.Close()
+
+	dir := t.TempDir()
+	engine := New()
+	engine.MaxMultipartMemory = 16 // the files are stored in temporary files
+	var saved []string
+	var uploaded []*multipart.FileHeader
+	engine.POST("/upload", func(c *Context) {
+		if c.PostForm("title") != "report" {
+			t.Errorf("unexpected title %q", c.PostForm("title"))
+		}
+
+		file, err := c.FormFile("files")
+		if err != nil || file.Size != int64(len("content of ../../evil.txt")) {
+			t.Fatalf("unexpected form file %v %v", file, err)
+		}
+
+		form, err := c.MultipartForm()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, file := range form.File["files"] {
+			if err := c.SaveUploadedFile(file, dir); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		uploaded = form.File["files"]
+
+		entries, _ := os.ReadDir(dir)
+		for _, entry := range entries {
+			saved = append(saved, entry.Name())
+		}
+	})
+
+	// the rewrite copies the request, the temporary files must be removed anyway
+	engine.Rewrite("/files", "/upload")
+	req := httptest.NewRequest(http.MethodPost, "/files", body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || fmt.Sprint(saved) != "[evil.txt photo.png]" {
+		t.Fatalf("the files should be saved in the directory, got %d %v", w.Code, saved)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "evil.txt"))
+	if err != nil || string(content) != "content of ../../evil.txt" {
+		t.Fatalf("unexpected content %q %v", content, err)
+	}
+
+	for _, file := range uploaded {
+		if f, err := file.Open(); err == nil {
+			f.Close()
+			t.Fatalf("the temporary file of %s should be removed", file.Filename)
+		}
+	}
+
+	// recent versions of mime/multipart already strip the directories of the filename
+	if name, err := uploadedFilename("../../evil.txt"); err != nil || name != "evil.txt" {
+		t.Fatalf("expected evil.txt, got %s %v", name, err)
+	}
+
+	if _, err := uploadedFilename(".."); err == nil {
+		t.Fatal(".. should not be a valid filename")
+	}
+}
+
+func TestAddRouteMidSegmentParams(t *testing.T) {
+	r := newRouter()
+	for _, pattern := range []string{"/files/:name.:ext", "/files/:name", "/v:version/users", "/v1/users"} {
+		if _, err := r.addRoute("GET", pattern, nil); err != nil {
+			t.Fatalf("%s should not conflict: %v", pattern, err)
+		}
+	}
+
+	_, err := r.addRoute("GET", "/files/:file.json", nil)
+	if conflict, ok := err.(*RouteConflictError); !ok || conflict.Existing != "/files/:name" {
+		t.Fatalf("/files/:file.json should conflict with /files/:name, got %v", err)
+	}
+
+	for _, pattern := range []string{"/x/:a:b", "/x/:a<int>:b", "/x/a:", "/at/12:30", "/x/:1st"} {
+		if _, err := r.addRoute("GET", pattern, nil); err == nil {
+			t.Fatalf("pattern %q should be rejected", pattern)
+		}
+	}
+
+	u, err := buildURL("/files/:name.:ext", "my report", "pdf")
+	if err != nil || u != "/files/my%20report.pdf" {
+		t.Fatalf("unexpected url %s %v", u, err)
+	}
+
+	u, err = buildURL(`/api/v1\:batchGet/:id`, 7)
+	if err != nil || u != "/api/v1:batchGet/7" {
+		t.Fatalf("unexpected url %s %v", u, err)
+	}
+}
+
+func TestEngineRoutes(t *testing.T) {
+	engine := New()
+	engine.Use(Recovery())
+	engine.GET("/users/:id", handlerTest1).Name("user")
+	api := engine.Group("/api", handlerTest2)
+	api.POST("/users", handlerTest1)
+	engine.Host("api.example.com").GET("/ping", handlerTest2)
+
+	routes := engine.Routes()
+	if len(routes) != 3 {
+		t.Fatalf("expected 3 routes, got %d", len(routes))
+	}
+
+	expected := []RouteInfo{
+		{Method: "POST", Path: "/api/users", Handler: nameOfFunction(handlerTest1),
+			Middlewares: []string{nameOfFunction(Recovery()), nameOfFunction(handlerTest2)}},
+		{Method: "GET", Path: "/users/:id", Name: "user", Handler: nameOfFunction(handlerTest1),
+			Middlewares: []string{nameOfFunction(Recovery())}},
+		{Method: "GET", Host: "api.example.com", Path: "/ping", Handler: nameOfFunction(handlerTest2),
+			Middlewares: []string{nameOfFunction(Recovery())}},
+	}
+
+	for i, route := range routes {
+		route.HandlerFunc = nil
+		if !reflect.DeepEqual(route, expected[i]) {
+			t.Fatalf("route %d: expected %+v, got %+v", i, expected[i], route)
+		}
+	}
+}
+
+func TestRemoveRoute(t *testing.T) {
+	r := newTestRouter()
+	r.addRoute("GET", "/hello/:name/profile", nil)
+	r.addRoute("POST", "/hello/:name", nil)
+	r.setName("hello", "/hello/:name")
+	before := r.load()
+
+	if !r.removeRoute("GET", "/hello/:name") {
+		t.Fatal("GET /hello/:name should be removed")
+	}
+
+	if r.removeRoute("GET", "/hello/:name") || r.removeRoute("GET", "/hello") || r.removeRoute("PUT", "/") {
+		t.Fatal("removing an unregistered route should report false")
+	}
+
+	if n := r.getRoute("GET", "/hello/geektutu", &Params{}); n != nil {
+		t.Fatalf("GET /hello/geektutu should not match, got %s", n.pattern)
+	}
+
+	for _, path := range []string{"/hello/geektutu/profile", "/hello/b/c"} {
+		if n := r.getRoute("GET", path, &Params{}); n == nil {
+			t.Fatalf("GET %s should still match", path)
+		}
+	}
+
+	if n := r.getRoute("POST", "/hello/geektutu", &Params{}); n == nil {
+		t.Fatal("POST /hello/geektutu should still match")
+	}
+
+	if before.roots["GET"].search("/hello/geektutu", &Params{}) == nil {
+		t.Fatal("the previous routing table should not be modified")
+	}
+
+	r.removeRoute("GET", "/hello/:name/profile")
+	if _, err := r.addRoute("GET", "/hello/:id", nil); err != nil {
+		t.Fatalf("the removed wildcard should not conflict anymore: %v", err)
+	}
+
+	if _, ok := r.load().names["hello"]; !ok {
+		t.Fatal("the name should be kept while POST /hello/:name is registered")
+	}
+
+	r.removeRoute("POST", "/hello/:name")
+	if _, ok := r.load().names["hello"]; ok {
+		t.Fatal("the name should be removed with the last route of the pattern")
+	}
+
+	r.removeRoute("GET", "/hello/:id")
+	r.removeRoute("GET", "/hello/b/c")
+	r.removeRoute("GET", "/hi/:name")
+	r.removeRoute("GET", "/assets/*filepath")
+	r.removeRoute("GET", "/")
+	if _, ok := r.load().roots["GET"]; ok || len(r.load().routes) != 0 {
+		t.Fatal("removing every route should empty the routing table")
+	}
+}
+
+func handlerTest1(c *Context) {}
+func handlerTest2(c *Context) {}
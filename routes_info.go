@@ -0,0 +1,117 @@
+package slim
+
+import (
+	"sort"
+)
+
+// RouteInfo represents a request route's specification which contains method and path and its handler.
+type RouteInfo struct {
+	Method      string
+	Host        string // host pattern given to Engine.Host, empty for the routes of the engine
+	Path        string
+	Name        string   // set by Route.Name
+	Handler     string   // name of the main handler
+	Middlewares []string // names of the handlers running before the main handler, in order
+	HandlerFunc HandlerFunc
+	Tags        []string               // set by Route.Tags
+	Meta        map[string]interface{} // set by Route.Meta, must not be modified
+}
+
+// RoutesInfo defines a RouteInfo slice.
+type RoutesInfo []RouteInfo
+
+// Routes returns a slice of registered routes, including some useful information, such as:
+// the http method, path, handler name and middleware names.
+// The routes of the engine come first, then the routes of each host and the
+// routes of each mounted engine in registration order, each sorted by path and method.
+func (engine *Engine) Routes() RoutesInfo {
+	routes := engine.router.routesInfo("")
+	for _, h := range engine.hosts {
+		routes = append(routes, h.router.routesInfo(h.pattern)...)
+	}
+
+	// the routes serving a mounted engine are replaced by the routes of the engine
+	visible := routes[:0]
+	for _, route := range routes {
+		if !engine.isMountRoute(route) {
+			visible = append(visible, route)
+		}
+	}
+
+	routes = visible
+	for _, m := range engine.mounts {
+		routes = append(routes, m.routesInfo()...)
+	}
+
+	return routes
+}
+
+// routesInfo returns the routes registered to r sorted by path and method.
+func (r *router) routesInfo(host string) RoutesInfo {
+	s := r.load()
+	routes := make(RoutesInfo, 0, len(s.routes))
+	for _, rt := range s.routes {
+		routes = append(routes, rt.info(host))
+	}
+
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+
+		return routes[i].Method < routes[j].Method
+	})
+
+	return routes
+}
+
+func (rt *route) info(host string) RouteInfo {
+	info := RouteInfo{
+		Method:      rt.method,
+		Host:        host,
+		Path:        rt.pattern,
+		Name:        rt.name,
+		HandlerFunc: rt.handlers.Last(),
+		Tags:        rt.meta.Tags,
+		Meta:        rt.meta.Values,
+	}
+
+	if len(rt.handlers) > 0 {
+		info.Handler = nameOfFunction(info.HandlerFunc)
+		info.Middlewares = make([]string, 0, len(rt.handlers)-1)
+		for _, h := range rt.handlers[:len(rt.handlers)-1] {
+			info.Middlewares = append(info.Middlewares, nameOfFunction(h))
+		}
+	}
+
+	return info
+}
+
+// DebugPrintRoutes prints the route table in debug mode, Engine.Run and Server.Run
+// call it before serving. Applications starting their own http.Server may call it too.
+// Each route is also printed as a row of the table when it is registered.
+func (engine *Engine) DebugPrintRoutes() {
+	debugPrintRouteTable(engine.Routes())
+}
+
+// debugPrintRouteTable prints all the registered routes in debug mode.
+func debugPrintRouteTable(routes RoutesInfo) {
+	if !IsDebugging() {
+		return
+	}
+
+	debugPrintf("%d routes registered:", len(routes))
+	for _, route := range routes {
+		debugPrintRoute(route)
+	}
+}
+
+// debugPrintRoute prints a row of the route table in debug mode.
+func debugPrintRoute(route RouteInfo) {
+	path := route.Path
+	if route.Host != "" {
+		path = route.Host + path
+	}
+
+	debugPrintf("%-7s %-40s --> %s (%d middlewares)", route.Method, path, route.Handler, len(route.Middlewares))
+}
@@ -60,6 +60,10 @@ func (s *Server) Run() {
 		panic("please set handler")
 	}
 
+	if engine, ok := s.server.Handler.(*Engine); ok {
+		engine.DebugPrintRoutes()
+	}
+
 	// 在独立协程中运行服务
 	s.logger.Printf("server run on: %s\n", s.address)
 	s.logger.Printf("server pid: %d\n", os.Getppid())
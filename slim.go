@@ -4,7 +4,7 @@ package slim
 import (
 	"html/template"
 	"net/http"
-	"strings"
+	"sync"
 )
 
 // HandlerFunc defines the handler used by slim middleware as return value.
@@ -25,19 +25,98 @@ func (c HandlersChain) Last() HandlerFunc {
 // Engine implement the interface of http.Handler
 type Engine struct {
 	*RouterGroup
+
+	// HandleMethodNotAllowed if enabled, the router checks if another method is allowed
+	// for the current route when the request cannot be routed.
+	// If this is the case, the request is answered with 'Method Not Allowed'
+	// and HTTP status code 405, the Allow header lists the allowed methods.
+	// If no other method is allowed, the request is delegated to the NoRoute handlers.
+	HandleMethodNotAllowed bool
+
+	// HandleOPTIONS if enabled, the router automatically replies to OPTIONS requests
+	// with the methods registered for the path in the Allow header, after the middleware
+	// of the group with the longest matching prefix, e.g. a CORS middleware.
+	// Routes registered for OPTIONS explicitly take precedence. Enabled by default.
+	HandleOPTIONS bool
+
+	// HandleHEAD if enabled, HEAD requests without a HEAD route are served by
+	// the GET route of the same path with the response body discarded.
+	// Routes registered for HEAD explicitly take precedence. Enabled by default.
+	HandleHEAD bool
+
+	// RedirectTrailingSlash enables automatic redirection if the current route can't be matched but a
+	// handler for the path with (without) the trailing slash exists.
+	// For example if /foo/ is requested but a route only exists for /foo, the
+	// client is redirected to /foo with http status code 301 for GET requests
+	// and 308 for all other request methods. Enabled by default.
+	RedirectTrailingSlash bool
+
+	// RedirectFixedPath if enabled, the router tries to fix the current request path, if no
+	// handle is registered for it.
+	// First superfluous path elements like ../ or // are removed.
+	// Afterwards the router does a case-insensitive lookup of the cleaned path.
+	// If a handle can be found for this route, the router makes a redirection
+	// to the corrected path with status code 301 for GET requests and 308 for
+	// all other request methods.
+	// For example /FOO and /..//Foo could be redirected to /foo.
+	// RedirectTrailingSlash is independent of this option.
+	RedirectFixedPath bool
+
+	// UseRawPath if enabled, the url.RawPath of the request is used to find the route when it is set,
+	// so that an escaped slash like in /files/a%2Fb does not separate two segments.
+	// Context.Path and Request.URL.Path stay unescaped.
+	UseRawPath bool
+
+	// UnescapePathValues if enabled, the param values matched against url.RawPath
+	// are unescaped individually, /files/a%2Fb gives "a/b" for /files/:name.
+	// It only applies when UseRawPath is enabled. Enabled by default.
+	UnescapePathValues bool
+
+	// MaxMultipartMemory is the number of bytes of the uploaded files kept in memory when
+	// parsing a multipart form, the rest is stored in temporary files. 32 MB by default.
+	MaxMultipartMemory int64
+
+	// SecureJSONPrefix is written by Context.SecureJSON before a JSON array,
+	// so that the response cannot be executed as a script. "while(1);" by default.
+	SecureJSONPrefix string
+
 	router        *router
 	noRoute       HandlersChain      // router not found chain
+	allNoRoute    HandlersChain      // engine middleware followed by noRoute
+	noMethod      HandlersChain      // method not allowed chain
+	allNoMethod   HandlersChain      // engine middleware followed by noMethod
 	groups        []*RouterGroup     // store all groups
+	hosts         []*hostRouter      // routers bound to a host pattern
+	mounts        []*mount           // engines mounted by RouterGroup.Mount
+	rewriter      *rewriter          // rules added by Engine.Rewrite, nil if none
 	htmlTemplates *template.Template // for html render
 	funcMap       template.FuncMap   // for html render func map
+	pool          sync.Pool          // recycles the contexts, see Context.Copy
 }
 
 // New is the constructor of gee.Engine
 func New() *Engine {
-	engine := &Engine{router: newRouter()}
+	engine := &Engine{
+		router:                newRouter(),
+		HandleOPTIONS:         true,
+		HandleHEAD:            true,
+		RedirectTrailingSlash: true,
+		UnescapePathValues:    true,
+		MaxMultipartMemory:    defaultMultipartMemory,
+		SecureJSONPrefix:      defaultSecureJSONPrefix,
+	}
+
 	engine.RouterGroup = &RouterGroup{engine: engine}
 	engine.groups = []*RouterGroup{engine.RouterGroup}
+	engine.pool.New = func() interface{} {
+		return engine.allocateContext()
+	}
+
 	engine.noRoute = []HandlerFunc{NoRoute()}
+	engine.noMethod = []HandlerFunc{NoMethod()}
+	engine.rebuild404Handlers()
+	engine.rebuild405Handlers()
+	engine.RouterGroup.rebuildOptionsHandlers()
 	return engine
 }
 
@@ -69,8 +148,20 @@ func (engine *Engine) SetFuncMap(funcMap template.FuncMap) {
 }
 
 // LoadHTMLGlob 加载templates文件
+// Besides the funcs set by SetFuncMap, templates can use {{ url "name" params... }}
+// to build route urls with Engine.URL.
 func (engine *Engine) LoadHTMLGlob(pattern string) {
-	engine.htmlTemplates = template.Must(template.New("").Funcs(engine.funcMap).ParseGlob(pattern))
+	engine.htmlTemplates = template.Must(template.New("").Funcs(engine.templateFuncMap()).ParseGlob(pattern))
+}
+
+// templateFuncMap returns the built-in template funcs merged with the ones set by SetFuncMap.
+func (engine *Engine) templateFuncMap() template.FuncMap {
+	funcMap := template.FuncMap{"url": engine.URL}
+	for name, fn := range engine.funcMap {
+		funcMap[name] = fn
+	}
+
+	return funcMap
 }
 
 // Run defines the method to start a http server
@@ -79,6 +170,7 @@ func (engine *Engine) Run(addr string, opts ...HTTPServerOption) (err error) {
 	server.Handler = engine
 	server.Addr = addr
 
+	engine.DebugPrintRoutes()
 	debugPrintf("server run address: %s", addr)
 
 	return server.ListenAndServe()
@@ -86,21 +178,118 @@ func (engine *Engine) Run(addr string, opts ...HTTPServerOption) (err error) {
 
 // ServeHTTP implement http ServeHTTP
 func (engine *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	// 合并所有的中间件
-	var middlewares HandlersChain
-	for _, group := range engine.groups {
-		if strings.HasPrefix(req.URL.Path, group.prefix) {
-			middlewares = append(middlewares, group.handlers...)
+	c := engine.pool.Get().(*Context)
+	c.reset(w, req)
+	if engine.rewriter == nil || !engine.rewriter.rewrite(c) {
+		engine.routerForHost(req.Host, &c.Params).handle(c)
+	}
+
+	// the server only removes the temporary files of the form parsed into req
+	removeMultipartForm(c.Request, req)
+	engine.pool.Put(c)
+}
+
+// allocateContext returns a new context whose Params fit the routes registered so far.
+func (engine *Engine) allocateContext() *Context {
+	maxParams := engine.router.load().maxParams
+	for _, h := range engine.hosts {
+		count := h.router.load().maxParams
+		for _, label := range h.labels {
+			if label[0] == ':' {
+				count++
+			}
+		}
+
+		if count > maxParams {
+			maxParams = count
 		}
 	}
 
-	c := newContext(w, req)
-	c.handlers = middlewares
-	c.engine = engine
-	engine.router.handle(c)
+	return &Context{engine: engine, Params: make(Params, 0, maxParams)}
+}
+
+// Use attaches a global middleware to the engine.
+// The middleware runs for the routes registered after this call and for NoRoute.
+func (engine *Engine) Use(middlewares ...HandlerFunc) {
+	engine.RouterGroup.Use(middlewares...)
+	engine.rebuild404Handlers()
+	engine.rebuild405Handlers()
 }
 
 // NoRoute adds handlers for NoRoute. It return a 404 code by default.
+// The engine middleware runs before these handlers, group middleware does not.
+// Groups may set their own handlers with RouterGroup.NoRoute.
 func (engine *Engine) NoRoute(handlers ...HandlerFunc) {
 	engine.noRoute = handlers
+	engine.rebuild404Handlers()
+}
+
+// NoMethod sets the handlers called when HandleMethodNotAllowed is enabled
+// and the path is registered for other methods only. It return a 405 code by default.
+// Groups may set their own handlers with RouterGroup.NoMethod.
+func (engine *Engine) NoMethod(handlers ...HandlerFunc) {
+	engine.noMethod = handlers
+	engine.rebuild405Handlers()
+}
+
+func (engine *Engine) rebuild404Handlers() {
+	engine.allNoRoute = engine.combineHandlers(engine.noRoute)
+}
+
+func (engine *Engine) rebuild405Handlers() {
+	engine.allNoMethod = engine.combineHandlers(engine.noMethod)
+}
+
+// rebuildGroupHandlers rebuilds the NoRoute, NoMethod and OPTIONS chains of the groups,
+// their middleware may have changed.
+func (engine *Engine) rebuildGroupHandlers() {
+	for _, group := range engine.groups {
+		group.rebuildOptionsHandlers()
+		if group.noRoute != nil {
+			group.allNoRoute = group.combineHandlers(group.noRoute)
+		}
+
+		if group.noMethod != nil {
+			group.allNoMethod = group.combineHandlers(group.noMethod)
+		}
+	}
+}
+
+// noRouteHandlers returns the NoRoute chain for path routed by r:
+// the one of the group with the longest matching prefix, or the one of the engine.
+func (engine *Engine) noRouteHandlers(r *router, path string) HandlersChain {
+	handlers, prefix := engine.allNoRoute, -1
+	for _, group := range engine.groups {
+		if group.allNoRoute != nil && len(group.prefix) >= prefix && group.covers(r, path) {
+			handlers, prefix = group.allNoRoute, len(group.prefix)
+		}
+	}
+
+	return handlers
+}
+
+// optionsHandlers returns the chain replying to an automatic OPTIONS request for path
+// routed by r, the one of the group with the longest matching prefix, so that the group
+// middleware like CORS sees the preflight requests.
+func (engine *Engine) optionsHandlers(r *router, path string) HandlersChain {
+	handlers, prefix := engine.RouterGroup.allOptions, -1
+	for _, group := range engine.groups {
+		if len(group.prefix) >= prefix && group.covers(r, path) {
+			handlers, prefix = group.allOptions, len(group.prefix)
+		}
+	}
+
+	return handlers
+}
+
+// noMethodHandlers returns the NoMethod chain for path routed by r, see noRouteHandlers.
+func (engine *Engine) noMethodHandlers(r *router, path string) HandlersChain {
+	handlers, prefix := engine.allNoMethod, -1
+	for _, group := range engine.groups {
+		if group.allNoMethod != nil && len(group.prefix) >= prefix && group.covers(r, path) {
+			handlers, prefix = group.allNoMethod, len(group.prefix)
+		}
+	}
+
+	return handlers
 }
@@ -3,6 +3,10 @@ package slim
 import (
 	"fmt"
 	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -94,3 +98,944 @@ func TestRunWithServer(t *testing.T) {
 
 	server.Run()
 }
+
+func TestRouteMiddleware(t *testing.T) {
+	var steps []string
+	auth := func(c *Context) {
+		steps = append(steps, "auth")
+		if c.GetHeader("Authorization") == "" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+		}
+	}
+
+	var names []string
+	engine := New()
+	engine.GET("/private", auth, func(c *Context) {
+		steps = append(steps, "handler")
+		names = c.HandlerNames()
+		c.String(http.StatusOK, "ok")
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/private", nil))
+	if w.Code != http.StatusUnauthorized || len(steps) != 1 {
+		t.Fatalf("auth middleware should abort the chain, got %d %v", w.Code, steps)
+	}
+
+	steps = nil
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/private", nil)
+	req.Header.Set("Authorization", "token")
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || len(steps) != 2 {
+		t.Fatalf("route chain should run auth then handler, got %d %v", w.Code, steps)
+	}
+
+	if len(names) != 2 || names[0] != nameOfFunction(auth) {
+		t.Fatalf("HandlerNames should reflect the route chain, got %v", names)
+	}
+}
+
+func TestGroupMiddlewareChain(t *testing.T) {
+	var steps []string
+	mark := func(name string) HandlerFunc {
+		return func(c *Context) {
+			steps = append(steps, name)
+		}
+	}
+
+	engine := New()
+	engine.Use(mark("engine"))
+	api := engine.Group("/api", mark("api"))
+	v1 := api.Group("/v1", mark("v1"))
+	v1.GET("/users", mark("users"))
+	engine.GET("/apiv2/users", mark("apiv2"))
+
+	tests := []struct {
+		path  string
+		steps string
+	}{
+		{"/api/v1/users", "[engine api v1 users]"},
+		{"/apiv2/users", "[engine apiv2]"},
+		{"/api/v1/unknown", "[engine]"},
+	}
+
+	for _, tt := range tests {
+		steps = nil
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if fmt.Sprint(steps) != tt.steps {
+			t.Fatalf("%s: expected %s, got %v", tt.path, tt.steps, steps)
+		}
+	}
+}
+
+func TestHandleMethodNotAllowed(t *testing.T) {
+	engine := New()
+	engine.GET("/users/:id", func(c *Context) {})
+	engine.PUT("/users/:id", func(c *Context) {})
+	engine.POST("/users", func(c *Context) {})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/users/1", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("405 handling is opt-in, got %d", w.Code)
+	}
+
+	engine.HandleMethodNotAllowed = true
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/users/1", nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "GET, HEAD, OPTIONS, PUT" {
+		t.Fatalf("expected 405 with Allow: GET, HEAD, OPTIONS, PUT, got %d %q", w.Code, w.Header().Get("Allow"))
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/orders", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("unknown path should still be 404, got %d", w.Code)
+	}
+
+	engine.NoMethod(func(c *Context) {
+		c.JSON(http.StatusMethodNotAllowed, H{"message": "method not allowed"})
+	})
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users", nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "OPTIONS, POST" ||
+		w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("custom NoMethod handlers should run, got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestHandleOptionsAndHead(t *testing.T) {
+	engine := New()
+	engine.GET("/users/:id", func(c *Context) {
+		c.SetHeader("X-User", c.Param("id"))
+		c.String(http.StatusOK, "user %s", c.Param("id"))
+	})
+	engine.DELETE("/users/:id", func(c *Context) {})
+	engine.OPTIONS("/custom", func(c *Context) {
+		c.Status(http.StatusTeapot)
+	})
+	engine.GET("/stream", func(c *Context) {
+		c.Status(http.StatusOK)
+		c.Writer.(http.Flusher).Flush()
+	})
+	api := engine.Group("/api", func(c *Context) {
+		c.SetHeader("Access-Control-Allow-Origin", "*")
+	})
+	api.POST("/users", func(c *Context) {})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/users/1", nil))
+	if w.Code != http.StatusNoContent || w.Header().Get("Allow") != "DELETE, GET, HEAD, OPTIONS" {
+		t.Fatalf("expected automatic OPTIONS reply, got %d %q", w.Code, w.Header().Get("Allow"))
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/api/users", nil))
+	if w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Fatalf("the group middleware should run for automatic OPTIONS replies, got %d %v", w.Code, w.Header())
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/custom", nil))
+	if w.Code != http.StatusTeapot {
+		t.Fatalf("explicit OPTIONS route should take precedence, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/users/1", nil))
+	if w.Code != http.StatusOK || w.Header().Get("X-User") != "1" || w.Body.Len() != 0 {
+		t.Fatalf("HEAD should run the GET handler without body, got %d %v %q", w.Code, w.Header(), w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/stream", nil))
+	if w.Code != http.StatusOK || !w.Flushed {
+		t.Fatalf("HEAD should keep http.Flusher of the writer, got %d %v", w.Code, w.Flushed)
+	}
+
+	engine.HandleOPTIONS = false
+	engine.HandleHEAD = false
+	for _, method := range []string{http.MethodOptions, http.MethodHead} {
+		w = httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(method, "/users/1", nil))
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("%s should not be handled automatically when disabled, got %d", method, w.Code)
+		}
+	}
+}
+
+func TestRedirectTrailingSlashAndFixedPath(t *testing.T) {
+	engine := New()
+	engine.GET("/users", func(c *Context) {})
+	engine.POST("/users", func(c *Context) {})
+	engine.GET("/docs/", func(c *Context) {})
+	engine.GET("/Users/:id/Profile", func(c *Context) {})
+
+	type redirectTest struct {
+		method   string
+		path     string
+		code     int
+		location string
+	}
+
+	check := func(tests []redirectTest) {
+		for _, tt := range tests {
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.code || w.Header().Get("Location") != tt.location {
+				t.Fatalf("%s %s: expected %d %q, got %d %q", tt.method, tt.path,
+					tt.code, tt.location, w.Code, w.Header().Get("Location"))
+			}
+		}
+	}
+
+	check([]redirectTest{
+		{http.MethodGet, "/users/", http.StatusMovedPermanently, "/users"},
+		{http.MethodGet, "/users/?page=2", http.StatusMovedPermanently, "/users?page=2"},
+		{http.MethodPost, "/users/", http.StatusPermanentRedirect, "/users"},
+		{http.MethodGet, "/docs", http.StatusMovedPermanently, "/docs/"},
+		{http.MethodGet, "/USERS", http.StatusNotFound, ""},
+	})
+
+	engine.RedirectFixedPath = true
+	check([]redirectTest{
+		{http.MethodGet, "/USERS", http.StatusMovedPermanently, "/users"},
+		{http.MethodGet, "/a/..//users", http.StatusMovedPermanently, "/users"},
+		{http.MethodPost, "/Users/", http.StatusPermanentRedirect, "/users"},
+		{http.MethodGet, "/users/Heige/profile", http.StatusMovedPermanently, "/Users/Heige/Profile"},
+		{http.MethodGet, "//users", http.StatusMovedPermanently, "/users"},
+	})
+
+	engine.RedirectTrailingSlash = false
+	engine.RedirectFixedPath = false
+	check([]redirectTest{
+		{http.MethodGet, "/users/", http.StatusNotFound, ""},
+	})
+
+	// the location is built from the escaped path
+	engine = New()
+	engine.GET("/:page", func(c *Context) {})
+	engine.GET("/files/:name", func(c *Context) {})
+	engine.RedirectFixedPath = true
+	check([]redirectTest{
+		{http.MethodGet, "/%5Cevil.com/", http.StatusMovedPermanently, "/%5Cevil.com"},
+		{http.MethodGet, "/%5C%5Cevil.com/", http.StatusMovedPermanently, "/%5C%5Cevil.com"},
+		{http.MethodGet, "/FILES/a%23b", http.StatusMovedPermanently, "/files/a%23b"},
+		{http.MethodGet, "/files/a%3Fb/", http.StatusMovedPermanently, "/files/a%3Fb"},
+		{http.MethodGet, "/files/a%20b/?x=1", http.StatusMovedPermanently, "/files/a%20b?x=1"},
+	})
+}
+
+func TestEngineHost(t *testing.T) {
+	engine := New()
+	engine.GET("/users", func(c *Context) {
+		c.String(http.StatusOK, "default")
+	})
+
+	api := engine.Host("api.example.com")
+	api.GET("/users", func(c *Context) {
+		c.String(http.StatusOK, "api")
+	})
+
+	tenant := engine.Host(":tenantID.Example.com").Group("/v1")
+	tenant.GET("/users/:id", func(c *Context) {
+		c.String(http.StatusOK, "tenant %s user %s", c.Param("tenantID"), c.Param("id"))
+	}).Name("tenant-user")
+
+	tests := []struct {
+		host string
+		path string
+		code int
+		body string
+	}{
+		{"example.com", "/users", http.StatusOK, "default"},
+		{"API.example.com:8080", "/users", http.StatusOK, "api"},
+		{"acme.example.com", "/v1/users/7", http.StatusOK, "tenant acme user 7"},
+		{"acme.example.com", "/users", http.StatusNotFound, ""},
+		{"a.b.example.com", "/v1/users/7", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Host = tt.host
+		engine.ServeHTTP(w, req)
+		if w.Code != tt.code || (tt.body != "" && w.Body.String() != tt.body) {
+			t.Fatalf("%s%s: expected %d %q, got %d %q", tt.host, tt.path, tt.code, tt.body, w.Code, w.Body)
+		}
+	}
+
+	engine.Host(":tenantID.EXAMPLE.com")
+	if len(engine.hosts) != 2 {
+		t.Fatalf("the static labels of a host pattern should be case-insensitive, got %d hosts", len(engine.hosts))
+	}
+
+	if u, err := engine.URL("tenant-user", "acme", 7); err != nil || u != "//acme.example.com/v1/users/7" {
+		t.Fatalf("expected //acme.example.com/v1/users/7, got %s %v", u, err)
+	}
+}
+
+func TestMount(t *testing.T) {
+	var steps []string
+	admin := New()
+	admin.Use(func(c *Context) {
+		steps = append(steps, "admin")
+	})
+	admin.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "admin index")
+	})
+	admin.GET("/users/:id", handlerTest1)
+
+	engine := New()
+	api := engine.Group("/api", func(c *Context) {
+		steps = append(steps, "api")
+	})
+	api.Mount("/admin", admin).Name("admin")
+	api.Mount("/files/", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("file " + req.URL.Path))
+	}))
+
+	tests := []struct {
+		path  string
+		body  string
+		steps string
+	}{
+		{"/api/admin", "admin index", "[api admin]"},
+		{"/api/admin/", "admin index", "[api admin]"},
+		{"/api/files/a/b.txt", "file /a/b.txt", "[api]"},
+	}
+
+	for _, tt := range tests {
+		steps = nil
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Body.String() != tt.body || fmt.Sprint(steps) != tt.steps {
+			t.Fatalf("%s: expected %q %s, got %q %v", tt.path, tt.body, tt.steps, w.Body, steps)
+		}
+	}
+
+	var paths []string
+	for _, route := range engine.Routes() {
+		if route.Method == http.MethodGet {
+			paths = append(paths, route.Path)
+		}
+
+		if route.Path == "/api/admin/users/:id" && len(route.Middlewares) != 2 {
+			t.Fatalf("mounted route should list the parent middleware, got %v", route.Middlewares)
+		}
+	}
+
+	if fmt.Sprint(paths) != "[/api/files /api/files/*mountpath /api/admin/ /api/admin/users/:id]" {
+		t.Fatalf("unexpected routes %v", paths)
+	}
+
+	if u, err := engine.URL("admin"); err != nil || u != "/api/admin" {
+		t.Fatalf("the mount name should build the prefix, got %s %v", u, err)
+	}
+
+	for _, method := range []string{http.MethodConnect, http.MethodTrace} {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(method, "/api/files/a.txt", nil))
+		if w.Body.String() == "file /a.txt" {
+			t.Fatalf("%s should not be served by a mounted handler", method)
+		}
+	}
+}
+
+func TestHostAddRemoveRoute(t *testing.T) {
+	engine := New()
+	engine.GET("/status", func(c *Context) { c.String(http.StatusOK, "engine") })
+	api := engine.Host("api.example.com").Group("/v1")
+	if _, err := api.AddRoute(http.MethodGet, "/status", func(c *Context) { c.String(http.StatusOK, "api") }); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(host, path string) string {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Host = host
+		engine.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	if body := get("api.example.com", "/v1/status"); body != "api" {
+		t.Fatalf("the route added to the host should be served, got %q", body)
+	}
+
+	if engine.RemoveRoute(http.MethodGet, "/v1/status") || !engine.RemoveRoute(http.MethodGet, "/status") {
+		t.Fatal("Engine.RemoveRoute should only remove the routes of the engine")
+	}
+
+	if !api.RemoveRoute(http.MethodGet, "/status") || api.RemoveRoute(http.MethodGet, "/status") {
+		t.Fatal("RemoveRoute on the host group should remove its route once")
+	}
+
+	if body := get("api.example.com", "/v1/status"); body == "api" {
+		t.Fatal("the removed host route should not be served")
+	}
+}
+
+func TestEngineAddRemoveRouteConcurrently(t *testing.T) {
+	engine := New()
+	engine.GET("/ping", func(c *Context) {
+		c.String(http.StatusOK, "pong")
+	})
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				w := httptest.NewRecorder()
+				engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+				if w.Code != http.StatusOK {
+					t.Errorf("/ping should always be served, got %d", w.Code)
+					return
+				}
+
+				w = httptest.NewRecorder()
+				engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/plugins/3/status", nil))
+				if w.Code != http.StatusOK && w.Code != http.StatusNotFound {
+					t.Errorf("/plugins/3/status: unexpected code %d", w.Code)
+					return
+				}
+
+				engine.Routes()
+			}
+		}()
+	}
+
+	for i := 0; i < 200; i++ {
+		path := fmt.Sprintf("/plugins/%d/status", i%10)
+		if _, err := engine.AddRoute(http.MethodGet, path, handlerTest1); err != nil {
+			t.Fatal(err)
+		}
+
+		// the routes registered by the last round are kept
+		if i < 190 && !engine.RemoveRoute(http.MethodGet, path) {
+			t.Fatalf("%s should be removed", path)
+		}
+	}
+
+	close(done)
+	wg.Wait()
+
+	if _, err := engine.AddRoute(http.MethodGet, "/ping", handlerTest1); err == nil {
+		t.Fatal("AddRoute should return the conflict error")
+	}
+
+	if _, err := engine.AddRoute("get", "/x", handlerTest1); err == nil {
+		t.Fatal("AddRoute should reject invalid methods")
+	}
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/plugins/3/status", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("/plugins/3/status should be registered, got %d", w.Code)
+	}
+}
+
+func TestRouteMeta(t *testing.T) {
+	engine := New()
+	engine.Use(func(c *Context) {
+		route := c.Route()
+		if perm := route.GetString("permission"); perm != "" && c.Request.Header.Get("X-Permission") != perm {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
+		if route.GetBool("deprecated") {
+			c.SetHeader("Deprecation", "true")
+		}
+	})
+
+	engine.NoRoute(func(c *Context) {
+		if c.Route() != nil {
+			t.Error("NoRoute should not have a matched route")
+		}
+
+		c.Status(http.StatusNotFound)
+	})
+
+	engine.GET("/users/:id", func(c *Context) {
+		route := c.Route()
+		c.String(http.StatusOK, "%s %s %v", route.Method, route.Path, route.HasTag("users"))
+	}).Tags("users", "public").Tags("users").Meta("summary", "get a user")
+	engine.DELETE("/users/:id", handlerTest1).Tags("users").Meta("permission", "users:write")
+	old := engine.Any("/v1/users", handlerTest1).Meta("deprecated", true)
+
+	tests := []struct {
+		method     string
+		path       string
+		permission string
+		code       int
+		body       string
+		deprecated string
+	}{
+		{http.MethodGet, "/users/1", "", http.StatusOK, "GET /users/:id true", ""},
+		{http.MethodDelete, "/users/1", "", http.StatusForbidden, "", ""},
+		{http.MethodDelete, "/users/1", "users:write", http.StatusOK, "", ""},
+		{http.MethodPut, "/v1/users", "", http.StatusOK, "", "true"},
+		{http.MethodGet, "/unknown", "", http.StatusNotFound, "", ""},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set("X-Permission", tt.permission)
+		engine.ServeHTTP(w, req)
+		if w.Code != tt.code || w.Body.String() != tt.body || w.Header().Get("Deprecation") != tt.deprecated {
+			t.Fatalf("%s %s: expected %d %q %q, got %d %q %q", tt.method, tt.path, tt.code, tt.body,
+				tt.deprecated, w.Code, w.Body, w.Header().Get("Deprecation"))
+		}
+	}
+
+	old.Meta("deprecated", false)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/users", nil))
+	if w.Header().Get("Deprecation") != "" {
+		t.Fatal("the metadata should be updated at runtime")
+	}
+
+	for _, route := range engine.Routes() {
+		if route.Method == http.MethodGet && route.Path == "/users/:id" {
+			if fmt.Sprint(route.Tags) != "[users public]" || route.Meta["summary"] != "get a user" {
+				t.Fatalf("unexpected route info %+v", route)
+			}
+		}
+	}
+}
+
+// discardWriter is a http.ResponseWriter that keeps nothing, so that the
+// benchmarks below only measure the engine.
+type discardWriter struct {
+	header http.Header
+}
+
+func (w *discardWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *discardWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (w *discardWriter) WriteHeader(int) {}
+
+func benchmarkServeHTTP(b *testing.B, path string) {
+	engine := New()
+	engine.Use(func(c *Context) {
+		c.Next()
+	})
+
+	for _, pattern := range benchRoutes() {
+		engine.GET(pattern, func(c *Context) {})
+	}
+
+	w := &discardWriter{header: make(http.Header)}
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		engine.ServeHTTP(w, req)
+	}
+}
+
+func BenchmarkServeHTTPStatic(b *testing.B) {
+	benchmarkServeHTTP(b, "/api/v1/warehouses/search")
+}
+
+func BenchmarkServeHTTPParams(b *testing.B) {
+	benchmarkServeHTTP(b, "/api/v1/tickets/42/comments/7")
+}
+
+func BenchmarkServeHTTPCatchAll(b *testing.B) {
+	benchmarkServeHTTP(b, "/api/v1/projects/3/attachments/docs/spec.pdf")
+}
+
+func TestContextPoolAndCopy(t *testing.T) {
+	engine := New()
+	copies := make(chan *Context, 1)
+	engine.GET("/users/:id", func(c *Context) {
+		if len(c.Errors) != 0 || c.Route() == nil {
+			t.Errorf("the context should be reset, got errors %v", c.Errors)
+		}
+
+		c.Error(fmt.Errorf("user %s", c.Param("id")))
+		copies <- c.Copy()
+	})
+	engine.GET("/other", func(c *Context) {})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	cp := <-copies
+
+	for _, path := range []string{"/users/2", "/other", "/unknown"} {
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	<-copies
+
+	value, ok := cp.Params.Get("id")
+	if !ok || value != "1" || cp.Param("id") != "1" || cp.Errors.String() == "" || cp.Route().Path != "/users/:id" {
+		t.Fatalf("the copy should keep the request data, got %v %v", cp.Params, cp.Errors)
+	}
+
+	if !cp.IsAborted() {
+		t.Fatal("the copy should not run handlers")
+	}
+
+	defer func() {
+		if r := recover(); r != copyWriterMessage {
+			t.Fatalf("writing from the copy should panic with %q, got %v", copyWriterMessage, r)
+		}
+	}()
+	cp.String(http.StatusOK, "late")
+}
+
+func TestUseRawPath(t *testing.T) {
+	newEngine := func(useRawPath, unescape bool) *Engine {
+		engine := New()
+		engine.UseRawPath = useRawPath
+		engine.UnescapePathValues = unescape
+		engine.GET("/files/:name", func(c *Context) {
+			c.String(http.StatusOK, "file %s", c.Param("name"))
+		})
+		engine.GET("/objects/:bucket/*key", func(c *Context) {
+			c.String(http.StatusOK, "object %s %s", c.Param("bucket"), c.Param("key"))
+		})
+		return engine
+	}
+
+	tests := []struct {
+		useRawPath bool
+		unescape   bool
+		path       string
+		code       int
+		body       string
+	}{
+		{false, true, "/files/a%2Fb", http.StatusNotFound, "404 not found: /files/a/b\n"},
+		{true, true, "/files/a%2Fb", http.StatusOK, "file a/b"},
+		{true, false, "/files/a%2Fb", http.StatusOK, "file a%2Fb"},
+		{true, true, "/files/hello%20world", http.StatusOK, "file hello world"},
+		{true, true, "/objects/my%2Fbucket/dir%2Fx/y%20z", http.StatusOK, "object my/bucket dir/x/y z"},
+		{false, true, "/objects/my%2Fbucket/dir%2Fx/y%20z", http.StatusOK, "object my bucket/dir/x/y z"},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		newEngine(tt.useRawPath, tt.unescape).ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.code || w.Body.String() != tt.body {
+			t.Fatalf("%s (raw %t, unescape %t): expected %d %q, got %d %q",
+				tt.path, tt.useRawPath, tt.unescape, tt.code, tt.body, w.Code, w.Body)
+		}
+	}
+}
+
+func TestGroupNoRoute(t *testing.T) {
+	var steps []string
+	engine := New()
+	engine.HandleMethodNotAllowed = true
+	engine.Use(func(c *Context) {
+		steps = append(steps, "engine")
+	})
+
+	api := engine.Group("/api", func(c *Context) {
+		steps = append(steps, "api")
+	})
+	api.NoRoute(func(c *Context) {
+		c.JSON(http.StatusNotFound, H{"error": "not found"})
+	})
+	api.NoMethod(func(c *Context) {
+		c.JSON(http.StatusMethodNotAllowed, H{"error": "method not allowed"})
+	})
+	api.GET("/users", handlerTest1)
+
+	v2 := api.Group("/v2/")
+	v2.NoRoute(func(c *Context) {
+		c.String(http.StatusNotFound, "v2 not found")
+	})
+	v2.Use(func(c *Context) {
+		steps = append(steps, "v2")
+	})
+
+	web := engine.Group("/web")
+	web.NoRoute(func(c *Context) {
+		c.SetHeader("Content-Type", "text/html")
+		c.Data(http.StatusNotFound, []byte("<h1>not found</h1>"))
+	})
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+		body   string
+		steps  string
+	}{
+		{http.MethodGet, "/api/unknown", http.StatusNotFound, "{\"error\":\"not found\"}\n", "[engine api]"},
+		{http.MethodGet, "/api", http.StatusNotFound, "{\"error\":\"not found\"}\n", "[engine api]"},
+		{http.MethodPost, "/api/users", http.StatusMethodNotAllowed, "{\"error\":\"method not allowed\"}\n", "[engine api]"},
+		{http.MethodGet, "/api/v2/unknown", http.StatusNotFound, "v2 not found", "[engine api v2]"},
+		{http.MethodGet, "/api/v2", http.StatusNotFound, "v2 not found", "[engine api v2]"},
+		{http.MethodGet, "/web/missing", http.StatusNotFound, "<h1>not found</h1>", "[engine]"},
+		{http.MethodGet, "/apiv2", http.StatusNotFound, "404 not found: /apiv2\n", "[engine]"},
+		{http.MethodGet, "/unknown", http.StatusNotFound, "404 not found: /unknown\n", "[engine]"},
+	}
+
+	for _, tt := range tests {
+		steps = nil
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.code || w.Body.String() != tt.body || fmt.Sprint(steps) != tt.steps {
+			t.Fatalf("%s %s: expected %d %q %s, got %d %q %v", tt.method, tt.path, tt.code, tt.body, tt.steps,
+				w.Code, w.Body, steps)
+		}
+	}
+}
+
+type userController struct{}
+
+func (userController) List(c *Context) {
+	c.String(http.StatusOK, "list users")
+}
+
+func (userController) Get(c *Context) {
+	c.String(http.StatusOK, "get user %s", c.Param("id"))
+}
+
+func (userController) Update(c *Context) {
+	c.String(http.StatusOK, "update user %s", c.Param("id"))
+}
+
+func (userController) Delete(c *Context) {
+	c.Status(http.StatusNoContent)
+}
+
+type postController struct{}
+
+func (*postController) Create(c *Context) {
+	c.String(http.StatusCreated, "create post of user %s", c.Param("uid"))
+}
+
+func TestResource(t *testing.T) {
+	var steps []string
+	engine := New()
+	api := engine.Group("/api")
+	api.Resource("/users/", userController{}, func(c *Context) {
+		steps = append(steps, "auth")
+	})
+	api.Resource("/users/:uid/posts", &postController{})
+	api.Resource("/posts", &postController{})
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+		body   string
+		steps  string
+	}{
+		{http.MethodGet, "/api/users", http.StatusOK, "list users", "[auth]"},
+		{http.MethodGet, "/api/users/7", http.StatusOK, "get user 7", "[auth]"},
+		{http.MethodPut, "/api/users/7", http.StatusOK, "update user 7", "[auth]"},
+		{http.MethodPatch, "/api/users/7", http.StatusOK, "update user 7", "[auth]"},
+		{http.MethodDelete, "/api/users/7", http.StatusNoContent, "", "[auth]"},
+		{http.MethodPost, "/api/users", http.StatusNotFound, "404 not found: /api/users\n", "[]"},
+		{http.MethodPost, "/api/users/7/posts", http.StatusCreated, "create post of user 7", "[]"},
+		{http.MethodPost, "/api/posts", http.StatusCreated, "create post of user ", "[]"},
+	}
+
+	for _, tt := range tests {
+		steps = nil
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.code || w.Body.String() != tt.body || fmt.Sprint(steps) != tt.steps {
+			t.Fatalf("%s %s: expected %d %q %s, got %d %q %v", tt.method, tt.path, tt.code, tt.body, tt.steps,
+				w.Code, w.Body, steps)
+		}
+	}
+
+	var routes []string
+	for _, route := range engine.Routes() {
+		routes = append(routes, fmt.Sprintf("%s %s %s %v %v %v", route.Method, route.Path, route.Name,
+			route.Tags, route.Meta["resource"], route.Meta["action"]))
+	}
+
+	expected := []string{
+		"POST /api/posts api.posts.create [posts] posts create",
+		"GET /api/users api.users.list [users] users list",
+		"DELETE /api/users/:id api.users.delete [users] users delete",
+		"GET /api/users/:id api.users.get [users] users get",
+		"PATCH /api/users/:id api.users.update [users] users update",
+		"PUT /api/users/:id api.users.update [users] users update",
+		"POST /api/users/:uid/posts api.users.posts.create [posts] posts create",
+	}
+	if fmt.Sprint(routes) != fmt.Sprint(expected) {
+		t.Fatalf("expected routes %v, got %v", expected, routes)
+	}
+
+	if u, err := engine.URL("api.users.get", 7); err != nil || u != "/api/users/7" {
+		t.Fatalf("expected /api/users/7, got %s %v", u, err)
+	}
+
+	if u, err := engine.URL("api.users.posts.create", 7); err != nil || u != "/api/users/7/posts" {
+		t.Fatalf("expected /api/users/7/posts, got %s %v", u, err)
+	}
+
+	// the same resource in two versions of the api
+	engine.Group("/v1").Resource("/users", userController{})
+	engine.Group("/v2").Resource("/users/", userController{})
+	if u, err := engine.URL("v2.users.get", 7); err != nil || u != "/v2/users/7" {
+		t.Fatalf("expected /v2/users/7, got %s %v", u, err)
+	}
+
+	root := New()
+	root.Resource("/", userController{})
+	w := httptest.NewRecorder()
+	root.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "list users" {
+		t.Fatalf("a resource at the root path should list at /, got %d %q", w.Code, w.Body)
+	}
+
+	if u, err := root.URL("root.get", 7); err != nil || u != "/7" {
+		t.Fatalf("expected /7, got %s %v", u, err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("a controller without any action should panic")
+		}
+	}()
+	api.Resource("/empty", struct{}{})
+}
+
+func TestRewrite(t *testing.T) {
+	engine := New()
+	engine.GET("/api/v1/users/:id", func(c *Context) {
+		c.String(http.StatusOK, "user %s %s %s", c.Param("id"), c.Request.URL.Path, c.Request.URL.Query().Get("tab"))
+	})
+	engine.GET("/help/*page", func(c *Context) {
+		c.String(http.StatusOK, "help %s", c.Param("page"))
+	})
+
+	engine.Rewrite("/v1/user/:id<int>", "/api/v1/users/:id")
+	engine.Rewrite("/v1/me", "/api/v1/users/0")
+	engine.Rewrite("/docs/*page", "/help/*page").Redirect(http.StatusMovedPermanently)
+	engine.Rewrite("/old/:name", "/new/:name").Redirect(http.StatusTemporaryRedirect)
+	engine.Rewrite("/go/*to", "/*to").Redirect(http.StatusFound)
+
+	tests := []struct {
+		method   string
+		path     string
+		code     int
+		body     string
+		location string
+	}{
+		{http.MethodGet, "/v1/user/123?tab=posts", http.StatusOK, "user 123 /api/v1/users/123 posts", ""},
+		{http.MethodGet, "/v1/me", http.StatusOK, "user 0 /api/v1/users/0 ", ""},
+		{http.MethodGet, "/v1/user/abc", http.StatusNotFound, "404 not found: /v1/user/abc\n", ""},
+		{http.MethodGet, "/api/v1/users/5", http.StatusOK, "user 5 /api/v1/users/5 ", ""},
+		{http.MethodGet, "/docs/a/b%20c?x=1", http.StatusMovedPermanently, "", "/help/a/b%20c?x=1"},
+		{http.MethodPost, "/old/a%20b", http.StatusTemporaryRedirect, "", "/new/a%20b"},
+		{http.MethodGet, "/go//evil.com", http.StatusFound, "", "/evil.com"},
+		{http.MethodGet, "/go/%5Cevil.com", http.StatusFound, "", "/%5Cevil.com"},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		body := w.Body.String()
+		if tt.location != "" {
+			body = ""
+		}
+
+		if w.Code != tt.code || body != tt.body || w.Header().Get("Location") != tt.location {
+			t.Fatalf("%s %s: expected %d %q %q, got %d %q %q", tt.method, tt.path, tt.code, tt.body, tt.location,
+				w.Code, w.Body, w.Header().Get("Location"))
+		}
+	}
+
+	engine.UseRawPath = true
+	engine.GET("/files/:name", func(c *Context) {
+		c.String(http.StatusOK, "file %s %s", c.Param("name"), c.Request.URL.EscapedPath())
+	})
+	engine.Rewrite("/f/:name", "/files/:name")
+	for path, expected := range map[string]string{
+		"/f/a%2Fb":  "file a/b /files/a%2Fb",
+		"/f/a%20b":  "file a b /files/a%20b",
+		"/f/report": "file report /files/report",
+	} {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Body.String() != expected {
+			t.Fatalf("%s: expected %q, got %q", path, expected, w.Body)
+		}
+	}
+
+	for _, rule := range [][2]string{{"/v1/user/:id<int>", "/x"}, {"/a/:id", "/b/:name"}, {"/a/:id", "b"}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%v should panic", rule)
+				}
+			}()
+			engine.Rewrite(rule[0], rule[1])
+		}()
+	}
+
+	for _, code := range []int{http.StatusOK, http.StatusMultipleChoices, http.StatusNotModified,
+		http.StatusUseProxy, 306, http.StatusBadRequest} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("redirect code %d should panic", code)
+				}
+			}()
+			rule := engine.Rewrite(fmt.Sprintf("/r%d/:name", code), "/redirect/:name")
+			rule.Redirect(code)
+		}()
+	}
+}
+
+func TestDebugPrintRoutes(t *testing.T) {
+	defer func(mode int, entry Logger) {
+		slimMode, LogEntry = mode, entry
+	}(slimMode, LogEntry)
+
+	var lines []string
+	slimMode = debugCode
+	LogEntry = LoggerFunc(func(format string, args ...interface{}) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	})
+
+	engine := New()
+	engine.GET("/users/:id", func(c *Context) {})
+	api := engine.Host("api.example.com")
+	api.Use(func(c *Context) { c.Next() })
+	api.GET("/status", func(c *Context) {})
+	var registered []string
+	for _, line := range lines {
+		if strings.Contains(line, " --> ") {
+			registered = append(registered, line)
+		}
+	}
+
+	if len(registered) != 2 || !strings.Contains(registered[0], "GET     /users/:id ") ||
+		!strings.Contains(registered[1], "api.example.com/status") || !strings.HasSuffix(registered[1], "(1 middlewares)\n") {
+		t.Fatalf("the routes should be printed when registered, got %q", lines)
+	}
+
+	lines = nil
+	engine.DebugPrintRoutes()
+	if len(lines) != 3 || lines[1] != registered[0] || lines[2] != registered[1] {
+		t.Fatalf("the route table should be printed, got %q", lines)
+	}
+}
@@ -43,6 +43,42 @@ func joinPaths(absolutePath, relativePath string) string {
 	return finalPath
 }
 
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cleanPath is the URL version of path.Clean, it returns a canonical URL path for p,
+// eliminating . and .. elements and duplicate slashes. A trailing slash is preserved.
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+
+	if p[0] != '/' {
+		p = "/" + p
+	}
+
+	np := path.Clean(p)
+	if lastChar(p) == '/' && np != "/" {
+		np += "/"
+	}
+
+	return np
+}
+
+// localPath returns p with its leading slashes and backslashes collapsed into one slash,
+// so that a redirect location never becomes a protocol-relative url like //evil.com,
+// browsers also read /\evil.com as //evil.com.
+func localPath(p string) string {
+	return "/" + strings.TrimLeft(p, "/\\")
+}
+
 func filterFlags(content string) string {
 	for i, char := range content {
 		if char == ' ' || char == ';' {
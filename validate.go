@@ -0,0 +1,317 @@
+package slim
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// FieldError describes a field that does not satisfy a rule of its validate tag.
+type FieldError struct {
+	Field string      `json:"field"`           // path of the field, e.g. items[1].name
+	Rule  string      `json:"rule"`            // rule name, e.g. min
+	Param string      `json:"param,omitempty"` // rule parameter, e.g. 3
+	Value interface{} `json:"-"`               // value of the field
+}
+
+// Error implements the error interface.
+func (e FieldError) Error() string {
+	if e.Param == "" {
+		return fmt.Sprintf("field '%s' failed on the '%s' rule", e.Field, e.Rule)
+	}
+
+	return fmt.Sprintf("field '%s' failed on the '%s=%s' rule", e.Field, e.Rule, e.Param)
+}
+
+// ValidationErrors lists the fields that failed validation, one error per field.
+type ValidationErrors []FieldError
+
+// Error implements the error interface.
+func (errs ValidationErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, e := range errs {
+		messages[i] = e.Error()
+	}
+
+	return "slim: validation failed: " + strings.Join(messages, "; ")
+}
+
+// meta returns the errors indexed by field, used as Error.Meta.
+func (errs ValidationErrors) meta() H {
+	meta := make(H, len(errs))
+	for _, e := range errs {
+		meta[e.Field] = e
+	}
+
+	return meta
+}
+
+// regexps caches the compiled patterns of the regexp rule.
+var regexps sync.Map
+
+// Validate checks the fields of the struct obj points to against their validate tag,
+// it is called by the bind methods of Context after decoding. The rules are comma separated:
+//
+//	required     the value is not the zero value, a pointer is not nil
+//	omitempty    the other rules are skipped for the zero value
+//	min=n max=n  minimum and maximum length of a string, slice or map, or value of a number
+//	len=n        exact length or value
+//	oneof=a b c  the string or integer is one of the space separated values
+//	email, url   the string is an email address or an absolute url
+//	regexp=re    the string matches re, it must be the last rule since re may contain commas
+//
+// Nested structs and the structs of slices are validated too. The fields are named after
+// their json tag, or else their name, e.g. items[1].name.
+// It returns ValidationErrors, or another error for an invalid rule.
+func Validate(obj interface{}) error {
+	var errs ValidationErrors
+	if err := validateNested(reflect.ValueOf(obj), "", &errs); err != nil {
+		return err
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// validateNested validates the structs in v.
+func validateNested(v reflect.Value, path string, errs *ValidationErrors) error {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() != timeType {
+			return validateStruct(v, path, errs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := validateNested(v.Index(i), fmt.Sprintf("%s[%d]", path, i), errs); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateStruct(v reflect.Value, prefix string, errs *ValidationErrors) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("validate")
+		if tag == "-" || (sf.PkgPath != "" && !sf.Anonymous) {
+			continue
+		}
+
+		path := prefix
+		if !sf.Anonymous {
+			path = joinFieldPath(prefix, validateFieldName(sf))
+		}
+
+		field := v.Field(i)
+		if tag != "" {
+			if err := validateField(field, path, tag, errs); err != nil {
+				return err
+			}
+		}
+
+		if err := validateNested(field, path, errs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateFieldName returns the name of the json tag, or else the field name.
+func validateFieldName(sf reflect.StructField) string {
+	if name := strings.Split(sf.Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+		return name
+	}
+
+	return sf.Name
+}
+
+func joinFieldPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+
+	return prefix + "." + name
+}
+
+// validateField checks v against the rules of tag and records the first failing rule.
+func validateField(v reflect.Value, path string, tag string, errs *ValidationErrors) error {
+	rules := splitRules(tag)
+	zero := v.IsZero()
+	if zero && containsString(rules, "omitempty") {
+		return nil
+	}
+
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	for _, rule := range rules {
+		name, param := rule, ""
+		if i := strings.IndexByte(rule, '='); i >= 0 {
+			name, param = rule[:i], rule[i+1:]
+		}
+
+		if name != "required" && v.Kind() == reflect.Ptr {
+			continue // a nil pointer only fails on required
+		}
+
+		ok, err := checkRule(v, name, param, zero)
+		if err != nil {
+			return fmt.Errorf("slim: invalid validate rule '%s' of field '%s': %v", rule, path, err)
+		}
+
+		if !ok {
+			e := FieldError{Field: path, Rule: name, Param: param}
+			if v.CanInterface() {
+				e.Value = v.Interface()
+			}
+
+			*errs = append(*errs, e)
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// splitRules splits tag on commas, except in the pattern of the regexp rule.
+func splitRules(tag string) []string {
+	var rules []string
+	for tag != "" {
+		if strings.HasPrefix(tag, "regexp=") {
+			return append(rules, tag)
+		}
+
+		rule := tag
+		if i := strings.IndexByte(tag, ','); i >= 0 {
+			rule, tag = tag[:i], tag[i+1:]
+		} else {
+			tag = ""
+		}
+
+		if rule = strings.TrimSpace(rule); rule != "" {
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules
+}
+
+// checkRule reports whether v satisfies the rule name with param.
+func checkRule(v reflect.Value, name string, param string, zero bool) (bool, error) {
+	switch name {
+	case "required":
+		return !zero, nil
+	case "omitempty":
+		return true, nil
+	case "min", "max", "len":
+		limit, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return false, err
+		}
+
+		n, err := measure(v)
+		if err != nil {
+			return false, err
+		}
+
+		switch name {
+		case "min":
+			return n >= limit, nil
+		case "max":
+			return n <= limit, nil
+		default:
+			return n == limit, nil
+		}
+	case "oneof":
+		var s string
+		switch v.Kind() {
+		case reflect.String:
+			s = v.String()
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			s = strconv.FormatInt(v.Int(), 10)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			s = strconv.FormatUint(v.Uint(), 10)
+		default:
+			return false, fmt.Errorf("unsupported type %s", v.Type())
+		}
+
+		return containsString(strings.Fields(param), s), nil
+	}
+
+	if v.Kind() != reflect.String {
+		return false, fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	s := v.String()
+	switch name {
+	case "email":
+		addr, err := mail.ParseAddress(s)
+		return err == nil && addr.Address == s, nil
+	case "url":
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != "" && u.Host != "", nil
+	case "regexp":
+		re, err := compileRegexp(param)
+		if err != nil {
+			return false, err
+		}
+
+		return re.MatchString(s), nil
+	}
+
+	return false, fmt.Errorf("unknown rule")
+}
+
+// measure returns the length of a string, slice or map, or the value of a number.
+func measure(v reflect.Value) (float64, error) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), nil
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	}
+
+	return 0, fmt.Errorf("unsupported type %s", v.Type())
+}
+
+func compileRegexp(pattern string) (*regexp.Regexp, error) {
+	if re, ok := regexps.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	regexps.Store(pattern, re)
+	return re, nil
+}
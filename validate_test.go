@@ -0,0 +1,137 @@
+package slim
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type validateItem struct {
+	SKU string `json:"sku" validate:"required,regexp=^[A-Z]{2,3}-[0-9]+$"`
+	Qty int    `json:"qty" validate:"min=1,max=99"`
+}
+
+type validateAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type validateOrder struct {
+	Email    string          `json:"email" validate:"required,email"`
+	Site     string          `json:"site" validate:"omitempty,url"`
+	Code     string          `json:"code" validate:"len=4"`
+	Status   string          `json:"status" validate:"oneof=new paid"`
+	Priority int             `json:"priority" validate:"oneof=1 2 3"`
+	Note     *string         `json:"note" validate:"max=5"`
+	Items    []validateItem  `json:"items" validate:"min=1"`
+	Address  validateAddress `json:"address"`
+	Billing  *validateAddress
+	Ignored  string `validate:"-"`
+}
+
+func TestValidate(t *testing.T) {
+	long := "too long"
+	valid := validateOrder{
+		Email:    "bob@example.com",
+		Code:     "AB12",
+		Status:   "paid",
+		Priority: 2,
+		Items:    []validateItem{{SKU: "AB-1", Qty: 3}},
+		Address:  validateAddress{City: "Paris"},
+	}
+
+	if err := Validate(&valid); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	invalid := validateOrder{
+		Email:    "bob",
+		Site:     "example.com",
+		Code:     "ABC",
+		Status:   "lost",
+		Priority: 4,
+		Note:     &long,
+		Items:    []validateItem{{SKU: "AB-1", Qty: 1}, {SKU: "ab-1,2", Qty: 100}},
+		Billing:  &validateAddress{},
+	}
+
+	err := Validate(&invalid)
+	errs, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+
+	want := []string{
+		"email:email", "site:url", "code:len=4", "status:oneof=new paid", "priority:oneof=1 2 3", "note:max=5",
+		"items[1].sku:regexp=^[A-Z]{2,3}-[0-9]+$", "items[1].qty:max=99", "address.city:required", "Billing.city:required",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %v", len(want), errs)
+	}
+
+	for i, e := range errs {
+		got := e.Field + ":" + e.Rule
+		if e.Param != "" {
+			got += "=" + e.Param
+		}
+
+		if got != want[i] {
+			t.Fatalf("error %d: expected %s, got %s", i, want[i], got)
+		}
+	}
+
+	if errs[0].Value != "bob" || !strings.Contains(err.Error(), "field 'email' failed on the 'email' rule") {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if err := Validate(&validateOrder{}); err == nil || len(err.(ValidationErrors)) != 6 {
+		t.Fatalf("expected the required, len, oneof and min rules of the zero value to fail, got %v", err)
+	}
+
+	var bad struct {
+		Flag bool `validate:"min=1"`
+	}
+	if err := Validate(&bad); err == nil || strings.HasPrefix(err.Error(), "slim: validation failed") {
+		t.Fatalf("expected an invalid rule error, got %v", err)
+	}
+}
+
+func TestBindValidate(t *testing.T) {
+	type signup struct {
+		Name  string `form:"name" json:"name" validate:"required,min=3"`
+		Email string `form:"email" json:"email" validate:"required,email"`
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"al","email":"al@example.com"}`))
+	req.Header.Set("Content-Type", MIMEJSON)
+	w := httptest.NewRecorder()
+	c := &Context{Request: req, Method: req.Method, Writer: w}
+
+	var s signup
+	err := c.Bind(&s)
+	if _, ok := err.(ValidationErrors); !ok {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+
+	if w.Code != http.StatusBadRequest || len(c.Errors) != 1 || !c.Errors[0].IsType(ErrorTypeBind) {
+		t.Fatalf("Bind should abort with 400 and record the error, got %d %v", w.Code, c.Errors)
+	}
+
+	meta := c.Errors[0].JSON().(H)
+	if fe, ok := meta["name"].(FieldError); !ok || fe.Rule != "min" || fe.Param != "3" || meta["error"] == nil {
+		t.Fatalf("unexpected error meta %v", meta)
+	}
+
+	c = &Context{Request: httptest.NewRequest(http.MethodGet, "/?name=alice&email=alice@example.com", nil), Method: http.MethodGet}
+	if err := c.ShouldBind(&s); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var uri struct {
+		ID int `uri:"id" validate:"min=1"`
+	}
+	c = &Context{Params: Params{{Key: "id", Value: "0"}}}
+	if err := c.ShouldBindURI(&uri); err == nil {
+		t.Fatal("ShouldBindURI should validate the params")
+	}
+}